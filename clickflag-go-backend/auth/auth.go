@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenVersion prefixes signed tokens so the format can change later
+const tokenVersion = "v1"
+
+// ErrUnauthorized is returned for missing, unknown, malformed or expired credentials
+var ErrUnauthorized = errors.New("invalid or expired credentials")
+
+// apiKey is a configured key, kept only as its SHA-256 digest
+type apiKey struct {
+	actor  string
+	digest [sha256.Size]byte
+}
+
+// Authenticator accepts static API keys and HMAC-signed tokens, and resolves them to an actor name
+type Authenticator struct {
+	keys   []apiKey
+	secret []byte
+	now    func() time.Time
+}
+
+// NewAuthenticator creates an authenticator for API keys by actor and, if secret is not
+// empty, for tokens signed with secret
+func NewAuthenticator(keysByActor map[string]string, secret string) *Authenticator {
+	a := &Authenticator{
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+	for actor, key := range keysByActor {
+		a.keys = append(a.keys, apiKey{actor: actor, digest: sha256.Sum256([]byte(key))})
+	}
+	return a
+}
+
+// Enabled reports whether any credential can be accepted
+func (a *Authenticator) Enabled() bool {
+	return len(a.keys) > 0 || len(a.secret) > 0
+}
+
+// Authenticate returns the actor of an API key or signed token
+func (a *Authenticator) Authenticate(credential string) (string, error) {
+	if credential == "" {
+		return "", ErrUnauthorized
+	}
+
+	if strings.HasPrefix(credential, tokenVersion+".") && len(a.secret) > 0 {
+		return a.verifyToken(credential)
+	}
+
+	// Every key is compared, in constant time, so timing does not reveal which one matched
+	digest := sha256.Sum256([]byte(credential))
+	actor := ""
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], key.digest[:]) == 1 {
+			actor = key.actor
+		}
+	}
+	if actor == "" {
+		return "", ErrUnauthorized
+	}
+	return actor, nil
+}
+
+// verifyToken checks a token's signature and expiry
+func (a *Authenticator) verifyToken(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return "", ErrUnauthorized
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil || !hmac.Equal(signature, sign(a.secret, parts[1], parts[2])) {
+		return "", ErrUnauthorized
+	}
+
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || a.now().Unix() >= expires {
+		return "", ErrUnauthorized
+	}
+
+	actor, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(actor) == 0 {
+		return "", ErrUnauthorized
+	}
+	return string(actor), nil
+}
+
+// SignToken creates a token for actor that is valid until expires
+func SignToken(secret, actor string, expires time.Time) (string, error) {
+	if secret == "" {
+		return "", errors.New("token secret is empty")
+	}
+	if actor == "" {
+		return "", errors.New("actor is empty")
+	}
+
+	encodedActor := base64.RawURLEncoding.EncodeToString([]byte(actor))
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	signature := base64.RawURLEncoding.EncodeToString(sign([]byte(secret), encodedActor, expiry))
+
+	return strings.Join([]string{tokenVersion, encodedActor, expiry, signature}, "."), nil
+}
+
+// sign returns the HMAC-SHA256 of a token's version, actor and expiry
+func sign(secret []byte, encodedActor, expiry string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(tokenVersion + "." + encodedActor + "." + expiry))
+	return mac.Sum(nil)
+}
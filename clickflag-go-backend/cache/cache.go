@@ -4,56 +4,198 @@ import (
 	"clickflag-go-backend/constants"
 	"clickflag-go-backend/models"
 	"maps"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// snapshotHistorySize is how many recent snapshots are kept for delta requests
+const snapshotHistorySize = 64
+
+// countrySnapshot is an immutable version of the country data
+type countrySnapshot struct {
+	version   uint64
+	countries map[string]*models.Country
+	encoded   *EncodedResponse
+}
+
+// EncodedResponse is the GetCountries response body of one snapshot, encoded once
+// per refresh. Gzip and Brotli are nil unless compression is enabled.
+type EncodedResponse struct {
+	Version uint64
+	JSON    []byte
+	Gzip    []byte
+	Brotli  []byte
+}
+
 // CountryCache handles country data operations (read-optimized)
 type CountryCache struct {
 	// Atomic value for countries data
-	countries atomic.Value // map[string]*models.Country
+	current atomic.Value // *countrySnapshot
+
+	// Serializes refreshes, so each one diffs against the snapshot the previous one stored
+	refreshMu sync.Mutex
+
+	// Recent snapshots for ChangedSince, oldest first (guarded by historyMu)
+	historyMu sync.RWMutex
+	history   []*countrySnapshot
+
+	// Whether refreshes also build gzip and brotli response bodies
+	compress atomic.Bool
+
+	// Unix nanoseconds of the last refresh, changed or not
+	refreshedAt atomic.Int64
 }
 
-// NewCountryCache creates a new country cache instance
+// NewCountryCache creates a new country cache instance.
+// Versions start at the creation time in milliseconds, so versions handed out
+// by a previous process are never mistaken for versions of this one.
 func NewCountryCache() *CountryCache {
-	initialCountries := make(map[string]*models.Country)
-
 	cc := &CountryCache{}
 
 	// Initialize atomic value
-	cc.countries.Store(initialCountries)
+	cc.current.Store(cc.newSnapshot(uint64(time.Now().UnixMilli()), make(map[string]*models.Country)))
 
 	return cc
 }
 
+// SetCompression enables or disables gzip and brotli bodies from the next refresh on
+func (cc *CountryCache) SetCompression(enabled bool) {
+	cc.compress.Store(enabled)
+}
+
+// newSnapshot creates a snapshot and encodes its response body
+func (cc *CountryCache) newSnapshot(version uint64, countries map[string]*models.Country) *countrySnapshot {
+	return &countrySnapshot{
+		version:   version,
+		countries: countries,
+		encoded:   encodeResponse(version, countries, cc.compress.Load()),
+	}
+}
+
+// GetEncodedResponse returns the pre-encoded GetCountries response of the current snapshot (lock-free read)
+func (cc *CountryCache) GetEncodedResponse() *EncodedResponse {
+	return cc.snapshot().encoded
+}
+
+// snapshot returns the current snapshot (lock-free read)
+func (cc *CountryCache) snapshot() *countrySnapshot {
+	return cc.current.Load().(*countrySnapshot)
+}
+
 // GetCountries returns all countries from cache (lock-free read)
 func (cc *CountryCache) GetCountries() map[string]*models.Country {
+	countries, _ := cc.GetSnapshot()
+	return countries
+}
+
+// GetSnapshot returns all countries together with the version they belong to (lock-free read)
+func (cc *CountryCache) GetSnapshot() (map[string]*models.Country, uint64) {
 	// Atomic load of countries
-	countries := cc.countries.Load().(map[string]*models.Country)
+	snapshot := cc.snapshot()
 
 	// Create a shallow copy to avoid race conditions (modern approach)
-	result := make(map[string]*models.Country, len(countries))
-	maps.Copy(result, countries)
-	return result
+	result := make(map[string]*models.Country, len(snapshot.countries))
+	maps.Copy(result, snapshot.countries)
+	return result, snapshot.version
+}
+
+// LastRefresh returns when the cache was last refreshed, or the zero time if never
+func (cc *CountryCache) LastRefresh() time.Time {
+	nanos := cc.refreshedAt.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Version returns the current snapshot version
+func (cc *CountryCache) Version() uint64 {
+	return cc.snapshot().version
+}
+
+// ChangedSince returns the current value of every country that changed after the
+// given version, together with the current version. ok is false when that version
+// is no longer (or was never) known; the caller should then send everything.
+func (cc *CountryCache) ChangedSince(version uint64) (changed map[string]int, current uint64, ok bool) {
+	latest := cc.snapshot()
+	if version == latest.version {
+		return map[string]int{}, latest.version, true
+	}
+
+	cc.historyMu.RLock()
+	var base *countrySnapshot
+	for _, snapshot := range cc.history {
+		if snapshot.version == version {
+			base = snapshot
+			break
+		}
+	}
+	cc.historyMu.RUnlock()
+
+	if base == nil {
+		return nil, latest.version, false
+	}
+
+	changed = make(map[string]int)
+	for code, country := range latest.countries {
+		if old, exists := base.countries[code]; !exists || old.Value != country.Value {
+			changed[code] = country.Value
+		}
+	}
+	return changed, latest.version, true
 }
 
 // RefreshCountries updates the cache with fresh data from database (atomic swap)
-func (cc *CountryCache) RefreshCountries(countries []models.Country) {
+// and returns how much the value of every changed country moved.
+// The snapshot version is increased only when something changed.
+func (cc *CountryCache) RefreshCountries(countries []models.Country) map[string]int {
+	cc.refreshMu.Lock()
+	defer cc.refreshMu.Unlock()
+
+	cc.refreshedAt.Store(time.Now().UnixNano())
+	old := cc.snapshot()
+
 	// Create new countries map
 	newCountries := make(map[string]*models.Country, len(countries))
+	changed := make(map[string]int)
 
 	for i := range countries {
 		country := countries[i]
 		newCountries[country.CountryCode] = &country
+
+		oldCountry, exists := old.countries[country.CountryCode]
+		switch {
+		case !exists:
+			changed[country.CountryCode] = country.Value
+		case oldCountry.Value != country.Value:
+			changed[country.CountryCode] = country.Value - oldCountry.Value
+		}
+	}
+
+	if len(changed) == 0 && len(newCountries) == len(old.countries) {
+		return changed
+	}
+
+	next := cc.newSnapshot(old.version+1, newCountries)
+
+	cc.historyMu.Lock()
+	cc.history = append(cc.history, next)
+	if len(cc.history) > snapshotHistorySize {
+		cc.history = cc.history[len(cc.history)-snapshotHistorySize:]
 	}
+	cc.historyMu.Unlock()
 
 	// Atomic swap of the countries
-	cc.countries.Store(newCountries)
+	cc.current.Store(next)
+
+	return changed
 }
 
 // GetCountryByCode returns a specific country from cache (lock-free read)
 func (cc *CountryCache) GetCountryByCode(countryCode string) (*models.Country, bool) {
 	// Atomic load to ensure we get consistent view
-	countries := cc.countries.Load().(map[string]*models.Country)
+	countries := cc.snapshot().countries
 
 	country, exists := countries[countryCode]
 	if !exists {
@@ -112,6 +254,14 @@ func (puc *PendingUpdatesCache) AddPendingUpdate(countryCode string) {
 	}
 }
 
+// AddPendingUpdateBy adds the given amount to a country's pending updates using atomic operations
+func (puc *PendingUpdatesCache) AddPendingUpdateBy(countryCode string, amount int32) {
+	counters := puc.counters.Load().(map[string]*CountryCounter)
+	if counter, exists := counters[countryCode]; exists {
+		atomic.AddInt32(&counter.Counter, amount)
+	}
+}
+
 // GetPendingUpdates returns all pending updates and clears them atomically
 func (puc *PendingUpdatesCache) GetPendingUpdates() map[string]int32 {
 	result := make(map[string]int32)
@@ -144,6 +294,7 @@ func (puc *PendingUpdatesCache) HasPendingUpdates() bool {
 type Cache struct {
 	countries      *CountryCache
 	pendingUpdates *PendingUpdatesCache
+	frozen         *FrozenCountries
 }
 
 // NewCache creates a new cache instance
@@ -151,6 +302,7 @@ func NewCache() *Cache {
 	return &Cache{
 		countries:      NewCountryCache(),
 		pendingUpdates: NewPendingUpdatesCache(),
+		frozen:         NewFrozenCountries(),
 	}
 }
 
@@ -161,19 +313,55 @@ func (c *Cache) GetCountries() map[string]*models.Country {
 	return c.countries.GetCountries()
 }
 
+// GetSnapshot returns all countries together with the version they belong to (lock-free read)
+func (c *Cache) GetSnapshot() (map[string]*models.Country, uint64) {
+	return c.countries.GetSnapshot()
+}
+
+// LastRefresh returns when the cache was last refreshed, or the zero time if never
+func (c *Cache) LastRefresh() time.Time {
+	return c.countries.LastRefresh()
+}
+
+// Version returns the current snapshot version
+func (c *Cache) Version() uint64 {
+	return c.countries.Version()
+}
+
+// GetEncodedResponse returns the pre-encoded GetCountries response of the current snapshot
+func (c *Cache) GetEncodedResponse() *EncodedResponse {
+	return c.countries.GetEncodedResponse()
+}
+
+// SetCompression enables or disables gzip and brotli bodies from the next refresh on
+func (c *Cache) SetCompression(enabled bool) {
+	c.countries.SetCompression(enabled)
+}
+
+// ChangedSince returns the countries that changed after the given version
+func (c *Cache) ChangedSince(version uint64) (map[string]int, uint64, bool) {
+	return c.countries.ChangedSince(version)
+}
+
 // AddPendingUpdate adds a country code to pending updates using atomic operations
 func (c *Cache) AddPendingUpdate(countryCode string) {
 	c.pendingUpdates.AddPendingUpdate(countryCode)
 }
 
+// AddPendingUpdateBy adds the given amount to a country's pending updates using atomic operations
+func (c *Cache) AddPendingUpdateBy(countryCode string, amount int32) {
+	c.pendingUpdates.AddPendingUpdateBy(countryCode, amount)
+}
+
 // GetPendingUpdates returns all pending updates and clears them atomically
 func (c *Cache) GetPendingUpdates() map[string]int32 {
 	return c.pendingUpdates.GetPendingUpdates()
 }
 
 // RefreshCountries updates the cache with fresh data from database (atomic swap)
-func (c *Cache) RefreshCountries(countries []models.Country) {
-	c.countries.RefreshCountries(countries)
+// and returns how much the value of every changed country moved
+func (c *Cache) RefreshCountries(countries []models.Country) map[string]int {
+	return c.countries.RefreshCountries(countries)
 }
 
 // GetCountryByCode returns a specific country from cache (lock-free read)
@@ -181,6 +369,21 @@ func (c *Cache) GetCountryByCode(countryCode string) (*models.Country, bool) {
 	return c.countries.GetCountryByCode(countryCode)
 }
 
+// SetFrozenCountries replaces the set of countries whose clicks are rejected
+func (c *Cache) SetFrozenCountries(codes []string) {
+	c.frozen.Replace(codes)
+}
+
+// IsFrozen reports whether clicks for a country are rejected (lock-free read)
+func (c *Cache) IsFrozen(countryCode string) bool {
+	return c.frozen.Contains(countryCode)
+}
+
+// FrozenCountries returns the countries whose clicks are rejected
+func (c *Cache) FrozenCountries() []string {
+	return c.frozen.List()
+}
+
 // HasPendingUpdates checks if there are any pending updates (atomic read)
 func (c *Cache) HasPendingUpdates() bool {
 	return c.pendingUpdates.HasPendingUpdates()
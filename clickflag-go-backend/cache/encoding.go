@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log/slog"
+
+	"clickflag-go-backend/models"
+
+	"github.com/andybalholm/brotli"
+)
+
+// encodeResponse builds the GetCountries response body of a snapshot
+func encodeResponse(version uint64, countries map[string]*models.Country, compress bool) *EncodedResponse {
+	countryMap := make(map[string]int, len(countries))
+	for _, country := range countries {
+		countryMap[country.CountryCode] = country.Value
+	}
+
+	body, err := json.Marshal(models.CountryResponse{
+		Success: true,
+		Message: "Countries retrieved successfully",
+		Version: version,
+		Data:    countryMap,
+	})
+	if err != nil {
+		// A map of ints always encodes; keep serving something valid regardless
+		slog.Error("Error encoding countries response", "error", err)
+		body = []byte(`{"success":false,"message":"Countries could not be encoded"}`)
+	}
+
+	encoded := &EncodedResponse{
+		Version: version,
+		JSON:    body,
+	}
+
+	if compress {
+		encoded.Gzip = gzipBytes(body)
+		encoded.Brotli = brotliBytes(body)
+	}
+
+	return encoded
+}
+
+// gzipBytes compresses data with gzip at the default compression level
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&buf, gzip.DefaultCompression)
+	if _, err := w.Write(data); err != nil {
+		return nil
+	}
+	if err := w.Close(); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// brotliBytes compresses data with brotli at the default compression level
+func brotliBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+	if _, err := w.Write(data); err != nil {
+		return nil
+	}
+	if err := w.Close(); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// FrozenCountries is the set of countries whose clicks are rejected. Reads are lock-free;
+// the set is replaced as a whole on every change.
+type FrozenCountries struct {
+	codes atomic.Pointer[map[string]struct{}]
+}
+
+// NewFrozenCountries creates an empty set
+func NewFrozenCountries() *FrozenCountries {
+	fc := &FrozenCountries{}
+	fc.Replace(nil)
+	return fc
+}
+
+// Replace makes codes the frozen countries
+func (fc *FrozenCountries) Replace(codes []string) {
+	set := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		set[code] = struct{}{}
+	}
+	fc.codes.Store(&set)
+}
+
+// Contains reports whether a country is frozen
+func (fc *FrozenCountries) Contains(countryCode string) bool {
+	_, frozen := (*fc.codes.Load())[countryCode]
+	return frozen
+}
+
+// List returns the frozen countries, sorted
+func (fc *FrozenCountries) List() []string {
+	set := *fc.codes.Load()
+	codes := make([]string, 0, len(set))
+	for code := range set {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/models"
+)
+
+// Backend reads and writes counts, either directly in the database or through the admin API
+// of a running server
+type Backend interface {
+	// Counts returns every country's count, ordered by country code
+	Counts(ctx context.Context) ([]models.CountryAPI, error)
+	// SetCount replaces a country's count and returns its audit entry
+	SetCount(ctx context.Context, countryCode string, value int) (models.AuditEntry, error)
+}
+
+// StoreBackend works directly on the database. A running server does not see the
+// changes until its next cache refresh, and its pending clicks are added on top.
+type StoreBackend struct {
+	store database.Store
+	actor string
+}
+
+// NewStoreBackend creates a backend on store that records changes as actor
+func NewStoreBackend(store database.Store, actor string) *StoreBackend {
+	return &StoreBackend{store: store, actor: actor}
+}
+
+// Counts returns every country's count from the store
+func (b *StoreBackend) Counts(ctx context.Context) ([]models.CountryAPI, error) {
+	countries, err := b.store.LoadCountries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]models.CountryAPI, len(countries))
+	for i, country := range countries {
+		counts[i] = models.CountryAPI{CountryCode: country.CountryCode, Value: country.Value}
+	}
+	sortCounts(counts)
+	return counts, nil
+}
+
+// SetCount replaces a country's count in the store
+func (b *StoreBackend) SetCount(ctx context.Context, countryCode string, value int) (models.AuditEntry, error) {
+	return b.store.SetCountryValue(ctx, countryCode, value, b.actor)
+}
+
+// APIBackend works through the admin API, so the server flushes pending clicks first
+// and serves the new counts right away
+type APIBackend struct {
+	baseURL    string
+	credential string
+	client     *http.Client
+}
+
+// NewAPIBackend creates a backend for the admin server at baseURL (e.g. http://localhost:9090),
+// authenticated with an API key or signed token
+func NewAPIBackend(baseURL, credential string) *APIBackend {
+	return &APIBackend{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		credential: credential,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Counts returns every country's count as currently served
+func (b *APIBackend) Counts(ctx context.Context) ([]models.CountryAPI, error) {
+	var counts []models.CountryAPI
+	if err := b.do(ctx, http.MethodGet, "/admin/v1/countries", nil, &counts); err != nil {
+		return nil, err
+	}
+	sortCounts(counts)
+	return counts, nil
+}
+
+// SetCount replaces a country's count through the admin API
+func (b *APIBackend) SetCount(ctx context.Context, countryCode string, value int) (models.AuditEntry, error) {
+	body, err := json.Marshal(models.AdminValueRequest{Value: &value})
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	var entry models.AuditEntry
+	err = b.do(ctx, http.MethodPut, "/admin/v1/countries/"+url.PathEscape(countryCode), strings.NewReader(string(body)), &entry)
+	return entry, err
+}
+
+// do sends an admin request and decodes the data of a successful response into data
+func (b *APIBackend) do(ctx context.Context, method, path string, body io.Reader, data any) error {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.credential)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Success bool            `json:"success"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("error decoding admin API response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || !response.Success {
+		return fmt.Errorf("admin API returned %d: %s", resp.StatusCode, response.Message)
+	}
+
+	if err := json.Unmarshal(response.Data, data); err != nil {
+		return fmt.Errorf("error decoding admin API data: %w", err)
+	}
+	return nil
+}
+
+// sortCounts orders counts by country code
+func sortCounts(counts []models.CountryAPI) {
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].CountryCode < counts[j].CountryCode
+	})
+}
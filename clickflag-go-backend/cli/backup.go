@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"clickflag-go-backend/settings"
+)
+
+// DefaultBackupPath returns backups/countries-<UTC timestamp>.db next to the database
+func DefaultBackupPath(databasePath string, now time.Time) string {
+	return filepath.Join(filepath.Dir(databasePath), "backups", "countries-"+now.UTC().Format("20060102-150405")+".db")
+}
+
+// Backup writes a consistent copy of a SQLite database to path with VACUUM INTO, which
+// is safe while the server is running. It never overwrites an existing file.
+func Backup(ctx context.Context, db *Database, path string) error {
+	if db.Driver != settings.DriverSQLite {
+		return fmt.Errorf("backup supports sqlite only; use pg_dump for %s", db.Driver)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file %s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error checking backup file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	if _, err := db.DB.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("error backing up database: %w", err)
+	}
+
+	return nil
+}
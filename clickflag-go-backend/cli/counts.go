@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"clickflag-go-backend/models"
+)
+
+// ListCounts prints every country's count as a table or as JSON
+func ListCounts(ctx context.Context, backend Backend, out io.Writer, asJSON bool) error {
+	counts, err := backend.Counts(ctx)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return writeJSON(out, counts)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "CODE\tVALUE\t")
+	total := 0
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%d\t\n", count.CountryCode, count.Value)
+		total += count.Value
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t\n", total)
+	return w.Flush()
+}
+
+// GetCount prints one country's count
+func GetCount(ctx context.Context, backend Backend, out io.Writer, countryCode string) error {
+	countryCode = strings.ToUpper(countryCode)
+	if !models.IsValidCountryCode(countryCode) {
+		return fmt.Errorf("invalid country code %q", countryCode)
+	}
+
+	counts, err := backend.Counts(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, count := range counts {
+		if count.CountryCode == countryCode {
+			fmt.Fprintln(out, count.Value)
+			return nil
+		}
+	}
+	return fmt.Errorf("country %s not found", countryCode)
+}
+
+// SetCount replaces one country's count and prints the change
+func SetCount(ctx context.Context, backend Backend, out io.Writer, countryCode string, value int) error {
+	countryCode = strings.ToUpper(countryCode)
+	if !models.IsValidCountryCode(countryCode) {
+		return fmt.Errorf("invalid country code %q", countryCode)
+	}
+	if value < 0 {
+		return fmt.Errorf("value must not be negative, got %d", value)
+	}
+
+	entry, err := backend.SetCount(ctx, countryCode, value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: %s -> %s (audit #%d by %s)\n", countryCode, formatOptional(entry.Before), formatOptional(entry.After), entry.ID, entry.Actor)
+	return nil
+}
+
+// formatOptional formats an optional audit value
+func formatOptional(value *int) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprint(*value)
+}
+
+// writeJSON writes indented JSON
+func writeJSON(out io.Writer, value any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
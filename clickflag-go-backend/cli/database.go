@@ -0,0 +1,75 @@
+// Package cli implements the clickflagctl operator commands
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"clickflag-go-backend/config"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/migrations"
+	"clickflag-go-backend/settings"
+)
+
+// Database is a connection to the configured database. Unlike the server, opening it
+// does not apply migrations.
+type Database struct {
+	DB            *sql.DB
+	Driver        string
+	Store         database.Store
+	migrationsDir string
+}
+
+// OpenDatabase connects to the database selected by DATABASE_DRIVER
+func OpenDatabase(cfg *config.Config) (*Database, error) {
+	switch cfg.DatabaseDriver {
+	case settings.DriverSQLite:
+		if err := database.Connect(cfg.DatabasePath); err != nil {
+			return nil, err
+		}
+		conn := database.GetDB()
+		return &Database{DB: conn, Driver: cfg.DatabaseDriver, Store: database.NewSQLiteStore(conn), migrationsDir: cfg.MigrationsDir}, nil
+
+	case settings.DriverPostgres:
+		conn, err := database.ConnectPostgres(cfg.DatabaseURL, PoolOptions(cfg))
+		if err != nil {
+			return nil, err
+		}
+		return &Database{DB: conn, Driver: cfg.DatabaseDriver, Store: database.NewPostgresStore(conn), migrationsDir: cfg.MigrationsDir}, nil
+
+	default:
+		return nil, fmt.Errorf("database driver %q is not supported (expected sqlite or postgres)", cfg.DatabaseDriver)
+	}
+}
+
+// PoolOptions returns the PostgreSQL connection pool settings of cfg
+func PoolOptions(cfg *config.Config) database.PoolOptions {
+	return database.PoolOptions{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	}
+}
+
+// NewDatabase wraps an open SQLite connection
+func NewDatabase(conn *sql.DB) *Database {
+	return &Database{DB: conn, Driver: settings.DriverSQLite, Store: database.NewSQLiteStore(conn)}
+}
+
+// Migrator returns a migrator for the database's dialect
+func (d *Database) Migrator() (*database.Migrator, error) {
+	if d.Driver == settings.DriverPostgres {
+		return database.NewPostgresMigrator(d.DB, d.migrationsDir)
+	}
+
+	if d.migrationsDir != "" {
+		return database.NewMigrator(d.DB, os.DirFS(d.migrationsDir))
+	}
+	return database.NewMigrator(d.DB, migrations.FS)
+}
+
+// Close closes the connection
+func (d *Database) Close() error {
+	return d.DB.Close()
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"clickflag-go-backend/models"
+)
+
+// Transfer formats of export and import
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// csvHeader is the first row of exported CSV files
+var csvHeader = []string{"country_code", "value"}
+
+// FormatFromPath returns the format implied by a file extension, or fallback
+func FormatFromPath(path, fallback string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	default:
+		return fallback
+	}
+}
+
+// Export writes every country's count as CSV or JSON
+func Export(ctx context.Context, backend Backend, out io.Writer, format string) error {
+	counts, err := backend.Counts(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSON:
+		return writeJSON(out, counts)
+
+	case FormatCSV:
+		w := csv.NewWriter(out)
+		if err := w.Write(csvHeader); err != nil {
+			return fmt.Errorf("error writing CSV: %w", err)
+		}
+		for _, count := range counts {
+			if err := w.Write([]string{count.CountryCode, strconv.Itoa(count.Value)}); err != nil {
+				return fmt.Errorf("error writing CSV: %w", err)
+			}
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		return fmt.Errorf("unknown format %q (expected csv or json)", format)
+	}
+}
+
+// ImportResult lists the countries an import changed
+type ImportResult struct {
+	Changed   []models.CountryAPI
+	Unchanged int
+}
+
+// Import reads counts in the export format and sets every count that differs. The whole
+// file is validated before anything is written; with dryRun nothing is written at all.
+func Import(ctx context.Context, backend Backend, in io.Reader, format string, dryRun bool) (ImportResult, error) {
+	counts, err := readCounts(in, format)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	current, err := backend.Counts(ctx)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	currentValues := make(map[string]int, len(current))
+	for _, count := range current {
+		currentValues[count.CountryCode] = count.Value
+	}
+
+	var result ImportResult
+	for _, count := range counts {
+		if value, exists := currentValues[count.CountryCode]; exists && value == count.Value {
+			result.Unchanged++
+			continue
+		}
+		result.Changed = append(result.Changed, count)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	for i, count := range result.Changed {
+		if _, err := backend.SetCount(ctx, count.CountryCode, count.Value); err != nil {
+			return ImportResult{Changed: result.Changed[:i], Unchanged: result.Unchanged}, fmt.Errorf("error setting %s: %w", count.CountryCode, err)
+		}
+	}
+
+	return result, nil
+}
+
+// readCounts parses and validates an import file
+func readCounts(in io.Reader, format string) ([]models.CountryAPI, error) {
+	var counts []models.CountryAPI
+
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(in).Decode(&counts); err != nil {
+			return nil, fmt.Errorf("error decoding JSON: %w", err)
+		}
+
+	case FormatCSV:
+		r := csv.NewReader(in)
+		r.FieldsPerRecord = 2
+		r.TrimLeadingSpace = true
+
+		for line := 1; ; line++ {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading CSV: %w", err)
+			}
+			if line == 1 && strings.EqualFold(record[0], csvHeader[0]) {
+				continue
+			}
+
+			value, err := strconv.Atoi(strings.TrimSpace(record[1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value %q", line, record[1])
+			}
+			counts = append(counts, models.CountryAPI{CountryCode: record[0], Value: value})
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected csv or json)", format)
+	}
+
+	seen := make(map[string]bool, len(counts))
+	for i := range counts {
+		code := strings.ToUpper(strings.TrimSpace(counts[i].CountryCode))
+		if !models.IsValidCountryCode(code) {
+			return nil, fmt.Errorf("invalid country code %q", counts[i].CountryCode)
+		}
+		if counts[i].Value < 0 {
+			return nil, fmt.Errorf("negative value %d for %s", counts[i].Value, code)
+		}
+		if seen[code] {
+			return nil, fmt.Errorf("duplicate country code %s", code)
+		}
+		seen[code] = true
+		counts[i].CountryCode = code
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"clickflag-go-backend/constants"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/migrations"
+)
+
+var (
+	// checkPattern matches the country code CHECK constraint of the countries table
+	checkPattern = regexp.MustCompile(`(?is)CHECK\s*\(\s*country_code\s+IN\s*\(([^)]*)\)`)
+	// seedPattern matches one seeded ('XX', 123) row
+	seedPattern = regexp.MustCompile(`\(\s*'([A-Z]{2})'\s*,\s*\d+\s*\)`)
+	// quotedCodePattern matches a quoted country code
+	quotedCodePattern = regexp.MustCompile(`'([A-Z]{2})'`)
+)
+
+// Comparison is the outcome of comparing one source of country codes with constants.AllCountryCodes
+type Comparison struct {
+	Source  string
+	Count   int
+	Missing []string // In constants but not in the source
+	Extra   []string // In the source but not in constants
+}
+
+// OK reports whether the source has exactly the country codes of the constants
+func (c Comparison) OK() bool {
+	return len(c.Missing) == 0 && len(c.Extra) == 0
+}
+
+// ValidationReport lists every comparison
+type ValidationReport struct {
+	Constants   int
+	Duplicates  []string // Codes listed more than once in constants
+	Comparisons []Comparison
+}
+
+// OK reports whether every source agrees with the constants
+func (r ValidationReport) OK() bool {
+	if len(r.Duplicates) > 0 {
+		return false
+	}
+	for _, comparison := range r.Comparisons {
+		if !comparison.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that constants.AllCountryCodes, the CHECK constraint and the seeded rows
+// of both the SQLite and the PostgreSQL migrations agree. The SQLite migrations are applied
+// to an in-memory database, so the result reflects every migration, not only the first.
+// When live is set, the rows of that database are compared as well.
+func Validate(ctx context.Context, live *Database) (ValidationReport, error) {
+	report := ValidationReport{
+		Constants:  len(constants.AllCountryCodes),
+		Duplicates: duplicates(constants.AllCountryCodes),
+	}
+
+	checkCodes, seededCodes, err := migratedSQLiteCodes(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Comparisons = append(report.Comparisons,
+		compareCodes("SQLite CHECK constraint", checkCodes),
+		compareCodes("SQLite seeded rows", seededCodes),
+	)
+
+	checkCodes, seededCodes, err = postgresMigrationCodes()
+	if err != nil {
+		return report, err
+	}
+	report.Comparisons = append(report.Comparisons,
+		compareCodes("PostgreSQL CHECK constraint", checkCodes),
+		compareCodes("PostgreSQL seeded rows", seededCodes),
+	)
+
+	if live != nil {
+		liveCodes, err := countryCodes(ctx, live.DB)
+		if err != nil {
+			return report, err
+		}
+		report.Comparisons = append(report.Comparisons, compareCodes(live.Driver+" database rows", liveCodes))
+	}
+
+	return report, nil
+}
+
+// PrintValidation writes a report in a human readable form
+func PrintValidation(out io.Writer, report ValidationReport) {
+	fmt.Fprintf(out, "constants.AllCountryCodes: %d codes\n", report.Constants)
+	if len(report.Duplicates) > 0 {
+		fmt.Fprintf(out, "  duplicate: %s\n", strings.Join(report.Duplicates, ", "))
+	}
+
+	for _, comparison := range report.Comparisons {
+		status := "OK"
+		if !comparison.OK() {
+			status = "MISMATCH"
+		}
+		fmt.Fprintf(out, "%-30s %3d codes  %s\n", comparison.Source+":", comparison.Count, status)
+		if len(comparison.Missing) > 0 {
+			fmt.Fprintf(out, "  missing: %s\n", strings.Join(comparison.Missing, ", "))
+		}
+		if len(comparison.Extra) > 0 {
+			fmt.Fprintf(out, "  extra:   %s\n", strings.Join(comparison.Extra, ", "))
+		}
+	}
+}
+
+// migratedSQLiteCodes applies the embedded SQLite migrations to an in-memory database and
+// returns the codes of its CHECK constraint and of its rows
+func migratedSQLiteCodes(ctx context.Context) (checkCodes, rowCodes []string, err error) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening in-memory database: %w", err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1) // Each connection would get its own in-memory database
+
+	migrator, err := database.NewMigrator(conn, migrations.FS)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := migrator.Up(); err != nil {
+		return nil, nil, fmt.Errorf("error applying migrations: %w", err)
+	}
+
+	var schema string
+	if err := conn.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'countries'`).Scan(&schema); err != nil {
+		return nil, nil, fmt.Errorf("error reading countries schema: %w", err)
+	}
+	checkCodes, err = parseCheckConstraint("SQLite countries schema", schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rowCodes, err = countryCodes(ctx, conn)
+	return checkCodes, rowCodes, err
+}
+
+// postgresMigrationCodes returns the codes of the CHECK constraint and of the seeded rows
+// in the PostgreSQL migrations, which cannot be applied without a server
+func postgresMigrationCodes() (checkCodes, seededCodes []string, err error) {
+	loaded, err := database.LoadMigrations(migrations.PostgresFS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, migration := range loaded {
+		// The last migration that declares the constraint wins
+		if checkPattern.MatchString(migration.UpSQL) {
+			source := fmt.Sprintf("PostgreSQL migration %03d", migration.Version)
+			if checkCodes, err = parseCheckConstraint(source, migration.UpSQL); err != nil {
+				return nil, nil, err
+			}
+		}
+		for _, match := range seedPattern.FindAllStringSubmatch(migration.UpSQL, -1) {
+			seededCodes = append(seededCodes, match[1])
+		}
+	}
+
+	if checkCodes == nil {
+		return nil, nil, fmt.Errorf("no country code CHECK constraint found in the PostgreSQL migrations")
+	}
+	return checkCodes, seededCodes, nil
+}
+
+// parseCheckConstraint returns the codes listed in a country code CHECK constraint
+func parseCheckConstraint(source, sqlText string) ([]string, error) {
+	match := checkPattern.FindStringSubmatch(sqlText)
+	if match == nil {
+		return nil, fmt.Errorf("no country code CHECK constraint found in %s", source)
+	}
+
+	var codes []string
+	for _, code := range quotedCodePattern.FindAllStringSubmatch(match[1], -1) {
+		codes = append(codes, code[1])
+	}
+	return codes, nil
+}
+
+// countryCodes returns the codes of the rows of the countries table
+func countryCodes(ctx context.Context, conn *sql.DB) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT country_code FROM countries`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying countries: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("error scanning country code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating countries: %w", err)
+	}
+	return codes, nil
+}
+
+// compareCodes compares a source's codes with constants.AllCountryCodes
+func compareCodes(source string, codes []string) Comparison {
+	expected := make(map[string]bool, len(constants.AllCountryCodes))
+	for _, code := range constants.AllCountryCodes {
+		expected[code] = true
+	}
+
+	actual := make(map[string]bool, len(codes))
+	comparison := Comparison{Source: source, Count: len(codes)}
+	for _, code := range codes {
+		actual[code] = true
+		if !expected[code] {
+			comparison.Extra = append(comparison.Extra, code)
+		}
+	}
+	for code := range expected {
+		if !actual[code] {
+			comparison.Missing = append(comparison.Missing, code)
+		}
+	}
+
+	sort.Strings(comparison.Missing)
+	sort.Strings(comparison.Extra)
+	return comparison
+}
+
+// duplicates returns the codes listed more than once
+func duplicates(codes []string) []string {
+	seen := make(map[string]int, len(codes))
+	var repeated []string
+	for _, code := range codes {
+		seen[code]++
+		if seen[code] == 2 {
+			repeated = append(repeated, code)
+		}
+	}
+	sort.Strings(repeated)
+	return repeated
+}
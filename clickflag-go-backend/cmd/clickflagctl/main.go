@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"clickflag-go-backend/cli"
+	"clickflag-go-backend/config"
+	"clickflag-go-backend/logging"
+)
+
+const usage = `clickflagctl: operate a ClickFlag database directly or through the admin API
+
+Usage:
+  clickflagctl [-config FILE] [-api URL -token TOKEN] [-actor NAME] <command> [arguments]
+
+Commands:
+  migrate up|down [steps]|status       Apply, roll back or list database migrations
+  counts list [-json]                  Print every country's count
+  counts get CODE                      Print one country's count
+  counts set CODE VALUE                Replace one country's count (audited)
+  export [-format csv|json] [-o FILE]  Write every count as CSV or JSON
+  import [-format csv|json] [-dry-run] FILE
+                                       Set every count that differs from FILE (audited)
+  backup [-o FILE]                     Copy the SQLite database with VACUUM INTO
+  validate [-live]                     Check that constants, CHECK constraints and seeded rows agree
+
+counts, export and import use the admin API when -api is set (or CLICKFLAG_API_URL),
+otherwise the database configured by DATABASE_DRIVER, DATABASE_PATH and DATABASE_URL
+(or database_driver, database_path and database_url in the -config file).
+
+Global flags:
+`
+
+// errUsage reports invalid arguments; usage has already been printed
+var errUsage = errors.New("invalid arguments")
+
+// errValidation reports that validate found mismatches; the report has already been printed
+var errValidation = errors.New("validation failed")
+
+// app holds the global flags shared by every command
+type app struct {
+	cfg   *config.Config
+	api   string
+	token string
+	actor string
+	out   io.Writer
+}
+
+// go run ./cmd/clickflagctl <command>
+func main() {
+	global := flag.NewFlagSet("clickflagctl", flag.ContinueOnError)
+	global.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		global.PrintDefaults()
+	}
+
+	a := &app{out: os.Stdout}
+	global.StringVar(&a.api, "api", os.Getenv("CLICKFLAG_API_URL"), "admin server URL, e.g. http://localhost:9090")
+	global.StringVar(&a.token, "token", os.Getenv("CLICKFLAG_API_TOKEN"), "admin API key or signed token")
+	global.StringVar(&a.actor, "actor", defaultActor(), "actor recorded in the audit log for direct database changes")
+	configPath := global.String("config", "", "server config file (YAML or TOML) with the database settings")
+
+	if err := global.Parse(os.Args[1:]); err != nil || global.NArg() == 0 {
+		if err == nil {
+			global.Usage()
+		}
+		os.Exit(2)
+	}
+
+	// Warnings and errors only, so command output stays readable
+	if _, err := logging.Setup(logging.Options{Level: "warn", Output: os.Stderr}); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	// The server's configuration layers, minus its command-line flags
+	var configArgs []string
+	if *configPath != "" {
+		configArgs = []string{"-config", *configPath}
+	}
+	cfg, _, err := config.Load(configArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	a.cfg = cfg
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = a.run(ctx, global.Arg(0), global.Args()[1:])
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case errors.Is(err, errValidation):
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches a command
+func (a *app) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "migrate":
+		return a.withDatabase(func(db *cli.Database) error {
+			return cli.Migrate(db, args, a.out)
+		})
+	case "counts":
+		return a.counts(ctx, args)
+	case "export":
+		return a.export(ctx, args)
+	case "import":
+		return a.importCounts(ctx, args)
+	case "backup":
+		return a.backup(ctx, args)
+	case "validate":
+		return a.validate(ctx, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		return errUsage
+	}
+}
+
+// counts handles `counts list|get|set`
+func (a *app) counts(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("counts list [-json] | get CODE | set CODE VALUE")
+	}
+
+	switch args[0] {
+	case "list":
+		flags := newFlagSet("counts list")
+		asJSON := flags.Bool("json", false, "print JSON instead of a table")
+		if err := flags.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		return a.withBackend(func(backend cli.Backend) error {
+			return cli.ListCounts(ctx, backend, a.out, *asJSON)
+		})
+
+	case "get":
+		if len(args) != 2 {
+			return usageError("counts get CODE")
+		}
+		return a.withBackend(func(backend cli.Backend) error {
+			return cli.GetCount(ctx, backend, a.out, args[1])
+		})
+
+	case "set":
+		if len(args) != 3 {
+			return usageError("counts set CODE VALUE")
+		}
+		value, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid value %q", args[2])
+		}
+		return a.withBackend(func(backend cli.Backend) error {
+			return cli.SetCount(ctx, backend, a.out, args[1], value)
+		})
+
+	default:
+		return usageError("counts list [-json] | get CODE | set CODE VALUE")
+	}
+}
+
+// export handles `export [-format csv|json] [-o FILE]`
+func (a *app) export(ctx context.Context, args []string) error {
+	flags := newFlagSet("export")
+	format := flags.String("format", "", "csv or json (default: from the file extension, else csv)")
+	output := flags.String("o", "", "output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *format == "" {
+		*format = cli.FormatFromPath(*output, cli.FormatCSV)
+	}
+
+	out := a.out
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return fmt.Errorf("error creating export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return a.withBackend(func(backend cli.Backend) error {
+		return cli.Export(ctx, backend, out, *format)
+	})
+}
+
+// importCounts handles `import [-format csv|json] [-dry-run] FILE`
+func (a *app) importCounts(ctx context.Context, args []string) error {
+	flags := newFlagSet("import")
+	format := flags.String("format", "", "csv or json (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "only print the countries that would change")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 1 {
+		return usageError("import [-format csv|json] [-dry-run] FILE")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = cli.FormatFromPath(path, "")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening import file: %w", err)
+	}
+	defer file.Close()
+
+	return a.withBackend(func(backend cli.Backend) error {
+		result, err := cli.Import(ctx, backend, file, *format, *dryRun)
+		for _, count := range result.Changed {
+			fmt.Fprintf(a.out, "%s = %d\n", count.CountryCode, count.Value)
+		}
+
+		verb := "Changed"
+		if *dryRun {
+			verb = "Would change"
+		}
+		fmt.Fprintf(a.out, "%s %d countries, %d unchanged\n", verb, len(result.Changed), result.Unchanged)
+		return err
+	})
+}
+
+// backup handles `backup [-o FILE]`
+func (a *app) backup(ctx context.Context, args []string) error {
+	flags := newFlagSet("backup")
+	output := flags.String("o", "", "backup file (default: backups/countries-<timestamp>.db next to the database)")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *output == "" {
+		*output = cli.DefaultBackupPath(a.cfg.DatabasePath, time.Now())
+	}
+
+	return a.withDatabase(func(db *cli.Database) error {
+		if err := cli.Backup(ctx, db, *output); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.out, "Backup written to", *output)
+		return nil
+	})
+}
+
+// validate handles `validate [-live]`
+func (a *app) validate(ctx context.Context, args []string) error {
+	flags := newFlagSet("validate")
+	live := flags.Bool("live", false, "also compare the rows of the configured database")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	check := func(db *cli.Database) error {
+		report, err := cli.Validate(ctx, db)
+		if err != nil {
+			return err
+		}
+		cli.PrintValidation(a.out, report)
+		if !report.OK() {
+			return errValidation
+		}
+		return nil
+	}
+
+	if *live {
+		return a.withDatabase(check)
+	}
+	return check(nil)
+}
+
+// withBackend runs fn on the admin API when -api is set, otherwise on the database
+func (a *app) withBackend(fn func(cli.Backend) error) error {
+	if a.api != "" {
+		if a.token == "" {
+			return fmt.Errorf("-token (or CLICKFLAG_API_TOKEN) is required with -api")
+		}
+		return fn(cli.NewAPIBackend(a.api, a.token))
+	}
+
+	return a.withDatabase(func(db *cli.Database) error {
+		return fn(cli.NewStoreBackend(db.Store, a.actor))
+	})
+}
+
+// withDatabase opens the configured database for fn
+func (a *app) withDatabase(fn func(*cli.Database) error) error {
+	db, err := cli.OpenDatabase(a.cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return fn(db)
+}
+
+// newFlagSet creates the flag set of a subcommand
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("clickflagctl "+name, flag.ContinueOnError)
+}
+
+// usageError prints a subcommand's usage
+func usageError(syntax string) error {
+	fmt.Fprintln(os.Stderr, "usage: clickflagctl", syntax)
+	return errUsage
+}
+
+// defaultActor names the operator in the audit log of direct database changes
+func defaultActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "clickflagctl:" + user
+	}
+	return "clickflagctl"
+}
@@ -2,117 +2,544 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"syscall"
-	"time"
 
+	"clickflag-go-backend/auth"
 	"clickflag-go-backend/cache"
+	"clickflag-go-backend/cli"
 	"clickflag-go-backend/config"
 	"clickflag-go-backend/database"
 	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/health"
+	"clickflag-go-backend/journal"
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/metrics"
 	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/models"
 	"clickflag-go-backend/processor"
-	"clickflag-go-backend/utils"
+	"clickflag-go-backend/proxyproto"
+	"clickflag-go-backend/ratelimit"
+	"clickflag-go-backend/settings"
+	"clickflag-go-backend/stream"
+	"clickflag-go-backend/tracing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 )
 
-// go run ./cmd/server/main.go
+// go run ./cmd/server
+// go run ./cmd/server migrate up|down [steps]|status (same as clickflagctl migrate)
 func main() {
-	// Load configuration first
-	cfg := config.Load()
+	// Load configuration first; every problem is reported at once
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Migration subcommand runs against the database and exits
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrateCommand(cfg, args[1:]); err != nil {
+			logging.Critical("Migration command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// JSON logs to stdout and the rotating log file; every package logs through the default logger
+	logFile, err := openLogFile(cfg)
+	if err != nil {
+		logging.Critical("Failed to open log file", "error", err)
+		os.Exit(1)
+	}
+	defer logFile.Close()
+
+	if _, err := logging.Setup(logging.Options{
+		Level:  cfg.LogLevel,
+		Output: io.MultiWriter(os.Stdout, logFile),
+	}); err != nil {
+		logging.Critical("Failed to initialize logger", "error", err)
+		os.Exit(1)
+	}
 
-	// Initialize logger with environment
-	if err := utils.InitLogger(cfg.Environment); err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+	// Tracing is off unless TRACE_EXPORTER selects an exporter
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TraceExporter,
+		Endpoint:    cfg.TraceEndpoint,
+		Insecure:    cfg.TraceInsecure,
+		ServiceName: "clickflag-backend",
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		logging.Critical("Failed to initialize tracing", "error", err)
+		os.Exit(1)
 	}
-	defer utils.CloseLogger()
 
-	utils.AppLogger.Info("Starting server with configuration: %+v", cfg)
+	slog.Info("Starting server",
+		"port", cfg.Port,
+		"admin_port", cfg.AdminPort,
+		"environment", cfg.Environment,
+		"database_driver", cfg.DatabaseDriver,
+		"log_level", cfg.LogLevel,
+		"trace_exporter", cfg.TraceExporter,
+	)
 
-	// Initialize database
-	if err := database.InitDatabase(cfg.DatabasePath); err != nil {
-		utils.AppLogger.Critical("Failed to initialize database: %v", err)
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Initialize storage backend
+	store, err := openStore(cfg)
+	if err != nil {
+		logging.Critical("Failed to initialize database", "error", err)
+		os.Exit(1)
 	}
-	defer database.CloseDatabase()
+	defer store.Close()
 
 	// Initialize cache
 	cacheInstance := cache.NewCache()
+	cacheInstance.SetCompression(cfg.CompressResponses)
 
 	// Load initial data from database to cache
-	log.Println("Loading initial data from database...")
-	countries, err := database.GetAllCountries()
+	slog.Info("Loading initial data from database")
+	countries, err := store.LoadCountries(context.Background())
 	if err != nil {
-		utils.AppLogger.Critical("Failed to load initial countries: %v", err)
-		log.Fatalf("Failed to load initial countries: %v", err)
+		logging.Critical("Failed to load initial countries", "error", err)
+		os.Exit(1)
 	}
 	cacheInstance.RefreshCountries(countries)
-	log.Printf("Loaded %d countries into cache", len(countries))
+	slog.Info("Loaded countries into cache", "countries", len(countries))
+
+	frozen, err := store.FrozenCountries(context.Background())
+	if err != nil {
+		logging.Critical("Failed to load frozen countries", "error", err)
+		os.Exit(1)
+	}
+	cacheInstance.SetFrozenCountries(frozen)
+	metrics.RegisterCacheRefreshAge(cacheInstance.LastRefresh)
+
+	// Open the click journal (optional) and replay clicks that were not committed before a crash
+	clickJournal, err := openJournal(cfg)
+	if err != nil {
+		logging.Critical("Failed to open click journal", "error", err)
+		os.Exit(1)
+	}
+	defer clickJournal.Close()
+
+	if _, err := clickJournal.Replay(cacheInstance.AddPendingUpdateBy); err != nil {
+		logging.Critical("Failed to replay click journal", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize background processor with the flush cron job (every 5 seconds by default)
+	bgProcessor := processor.NewBackgroundProcessor(cacheInstance, store, clickJournal, cfg.FlushSchedule, cfg.RecoveryFilePath)
+
+	// Replay clicks spilled by a previous shutdown; the file is removed once a flush writes them
+	if _, err := bgProcessor.ReplayRecovery(); err != nil {
+		slog.Error("Failed to replay recovery file", "path", cfg.RecoveryFilePath, "error", err)
+	}
+
+	// Fan out changed counts to live stream subscribers after every flush
+	broadcaster := stream.NewBroadcaster(cfg.StreamMaxClients, 64)
+	bgProcessor.SetBroadcaster(broadcaster)
 
-	// Initialize background processor with cron job (every 5 seconds)
-	bgProcessor := processor.NewBackgroundProcessor(cacheInstance, "*/5 * * * * *")
 	bgProcessor.Start()
-	defer bgProcessor.Stop()
+
+	// Roll minute history up into hours and days and prune old buckets (every minute by default)
+	historyMaintainer := processor.NewHistoryMaintainer(store, database.RetentionPolicy{
+		Minute: cfg.HistoryMinuteTTL,
+		Hour:   cfg.HistoryHourTTL,
+		Day:    cfg.HistoryDayTTL,
+	}, cfg.HistorySchedule)
+	historyMaintainer.Start()
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "ClickFlag Go Backend",
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		IdleTimeout:  3 * time.Second,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
 	})
 
+	// Client IPs come from CLIENT_IP_HEADER only when it was added by a trusted proxy
+	clientIP, err := middleware.NewClientIPResolver(cfg.TrustedProxies, cfg.ClientIPHeader)
+	if err != nil {
+		logging.Critical("Invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+
 	// Setup middleware
-	middleware.SetupMiddleware(app)
+	runtimeMiddleware := middleware.SetupMiddleware(app, middleware.Options{
+		ClientIP:         clientIP,
+		RequestTimeout:   cfg.RequestTimeout,
+		CORS:             corsPolicy(cfg),
+		ReadCORSOrigins:  cfg.CORSReadOrigins,
+		PublicReadRoutes: publicReadRoutes,
+	})
 
 	// Initialize handlers
-	countryHandler := handlers.NewCountryHandler(cacheInstance)
+	countryHandler := handlers.NewCountryHandler(cacheInstance, clickJournal)
+	clickRule, clickCountryRule := clickRules(cfg)
+	clickLimiter := ratelimit.NewLimiter(clickRule, clickCountryRule, cfg.RateLimitMaxKeys)
+	countryHandler.SetClickLimiter(clickLimiter)
+	readLimiter := ratelimit.NewLimiter(readRule(cfg), ratelimit.Rule{}, cfg.RateLimitMaxKeys)
+	healthHandler := handlers.NewHealthHandler(cacheInstance, newHealthChecker(cfg, store, cacheInstance, bgProcessor))
+	batchHandler := handlers.NewBatchHandler(cacheInstance, clickJournal, handlers.BatchLimits{
+		ClientRate:  float64(cfg.BatchClientRate),
+		CountryRate: float64(cfg.BatchCountryRate),
+		Window:      cfg.BatchWindow,
+		MaxKeys:     cfg.RateLimitMaxKeys,
+	})
+	historyHandler := handlers.NewHistoryHandler(store)
+	streamHandler := handlers.NewStreamHandler(cacheInstance, broadcaster, cfg.StreamHeartbeat)
+	wsHandler := handlers.NewWebSocketHandler(cacheInstance, clickJournal, broadcaster, cfg.StreamHeartbeat, float64(cfg.WSClickRate), cfg.WSClickBurst)
+	wsHandler.SetClickLimiter(clickLimiter)
 
 	// Setup routes
-	setupRoutes(app, countryHandler)
+	setupRoutes(app, readLimiter, countryHandler, healthHandler, batchHandler, historyHandler, streamHandler, wsHandler)
+
+	listener, err := listen(app, cfg, clientIP)
+	if err != nil {
+		logging.Critical("Failed to start server", "error", err)
+		os.Exit(1)
+	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
-		if err := app.Listen(":" + cfg.Port); err != nil {
-			utils.AppLogger.Critical("Failed to start server: %v", err)
-			log.Fatalf("Failed to start server: %v", err)
+		slog.Info("Server starting", "port", cfg.Port, "client_ip_header", cfg.ClientIPHeader)
+		if err := app.Listener(listener); err != nil {
+			logging.Critical("Failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
+	// Admin API credentials; without any, /admin/v1 is not served
+	adminKeys, err := settings.ParseAPIKeys(cfg.AdminAPIKeys)
+	if err != nil {
+		logging.Critical("Invalid ADMIN_API_KEYS", "error", err)
+		os.Exit(1)
+	}
+	authenticator := auth.NewAuthenticator(adminKeys, cfg.AdminTokenSecret)
+	adminHandler := handlers.NewAdminHandler(store, cacheInstance, bgProcessor)
+
+	// SIGHUP and POST /admin/v1/config/reload re-read the configuration and swap in runtime settings
+	reloader := config.NewReloader(cfg, os.Args[1:])
+	reloader.OnReload(func(old, updated *config.Config) error {
+		return applyRuntimeConfig(old, updated, runtimeMiddleware, clickLimiter, readLimiter, bgProcessor)
+	})
+	reloader.OnReload(func(old, updated *config.Config) error {
+		return reloadFrozenCountries(store, cacheInstance)
+	})
+	adminHandler.SetReloader(reloader)
+
+	// Admin server (metrics and admin API) on its own port, so it is not exposed with the public API
+	adminApp := newAdminApp(authenticator, adminHandler)
+	if cfg.AdminPort != 0 {
+		go func() {
+			slog.Info("Admin server starting", "port", cfg.AdminPort)
+			if err := adminApp.Listen(fmt.Sprintf(":%d", cfg.AdminPort)); err != nil {
+				slog.Error("Admin server failed", "error", err)
+			}
+		}()
+	}
+
+	go reloadOnSIGHUP(reloader, store)
+
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// End open live streams so they do not hold up the shutdown
+	broadcaster.Close()
+
+	// Graceful shutdown: stop accepting requests and drain in-flight ones (including AddCountry)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := app.ShutdownWithContext(ctx); err != nil {
-		log.Printf("Error during server shutdown: %v", err)
+		slog.Error("Error during server shutdown", "error", err)
+	}
+
+	// WebSocket connections are hijacked and outlive the HTTP shutdown; close them so no
+	// click is recorded after the final flush
+	if err := wsHandler.Shutdown(ctx); err != nil {
+		slog.Error("Error closing WebSocket connections", "error", err)
+	}
+
+	historyMaintainer.Stop()
+
+	if err := adminApp.ShutdownWithContext(ctx); err != nil {
+		slog.Error("Error during admin server shutdown", "error", err)
+	}
+
+	// Final flush of pending clicks, with its own deadline
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer flushCancel()
+
+	if err := bgProcessor.Shutdown(flushCtx); err != nil {
+		logging.Critical("Failed to flush pending updates on shutdown", "error", err)
+	}
+
+	// Export spans still buffered, including the final flush
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
 	}
 
-	log.Println("Server stopped gracefully")
+	slog.Info("Server stopped gracefully")
+}
+
+// reloadOnSIGHUP reloads the configuration on every SIGHUP and records it in the audit log
+func reloadOnSIGHUP(reloader *config.Reloader, store database.Store) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		result, err := reloader.Reload()
+		detail := ""
+		if result != nil {
+			detail = result.String()
+			for _, change := range result.RestartRequired {
+				slog.Warn("Setting changed but needs a restart", "key", change.Key, "running", change.Old, "configured", change.New)
+			}
+		}
+		if err != nil {
+			slog.Error("Configuration reload failed", "error", err)
+			detail = strings.TrimPrefix(detail+"; "+err.Error(), "; ")
+		} else {
+			slog.Info("Configuration reloaded", "detail", detail)
+		}
+
+		if _, err := store.RecordAudit(context.Background(), models.AuditEntry{
+			Actor:  "SIGHUP",
+			Action: models.AuditReload,
+			Detail: detail,
+		}); err != nil {
+			slog.Error("Failed to record configuration reload", "error", err)
+		}
+	}
+}
+
+// listen opens the public listener; with the PROXY protocol, trusted proxies send the
+// client address ahead of each connection
+func listen(app *fiber.App, cfg *config.Config, clientIP *middleware.ClientIPResolver) (net.Listener, error) {
+	listener, err := net.Listen(app.Config().Network, fmt.Sprintf(":%d", cfg.Port))
+	if err != nil {
+		return nil, fmt.Errorf("error listening on port %d: %w", cfg.Port, err)
+	}
+
+	if cfg.ClientIPHeader != settings.ClientIPProxyProtocol {
+		return listener, nil
+	}
+	return proxyproto.NewListener(listener, clientIP.IsTrusted, cfg.ReadTimeout), nil
+}
+
+// applyRuntimeConfig swaps in the settings of updated that can change without a restart
+func applyRuntimeConfig(old, updated *config.Config, runtimeMiddleware *middleware.Runtime, clickLimiter, readLimiter *ratelimit.Limiter, bgProcessor *processor.BackgroundProcessor) error {
+	oldClick, oldCountry := clickRules(old)
+	clickRule, clickCountryRule := clickRules(updated)
+	if clickRule != oldClick || clickCountryRule != oldCountry || updated.RateLimitMaxKeys != old.RateLimitMaxKeys {
+		clickLimiter.Reconfigure(clickRule, clickCountryRule, updated.RateLimitMaxKeys)
+	}
+	if readRule(updated) != readRule(old) || updated.RateLimitMaxKeys != old.RateLimitMaxKeys {
+		readLimiter.Reconfigure(readRule(updated), ratelimit.Rule{}, updated.RateLimitMaxKeys)
+	}
+	if !reflect.DeepEqual(corsPolicy(updated), corsPolicy(old)) || !reflect.DeepEqual(updated.CORSReadOrigins, old.CORSReadOrigins) {
+		runtimeMiddleware.SetCORS(corsPolicy(updated), updated.CORSReadOrigins)
+	}
+	if err := logging.SetLevel(updated.LogLevel); err != nil {
+		return err
+	}
+	if updated.FlushSchedule != old.FlushSchedule {
+		return bgProcessor.SetSchedule(updated.FlushSchedule)
+	}
+	return nil
+}
+
+// reloadFrozenCountries reads the frozen countries again, e.g. after clickflagctl changed them
+func reloadFrozenCountries(store database.Store, cacheInstance *cache.Cache) error {
+	frozen, err := store.FrozenCountries(context.Background())
+	if err != nil {
+		return fmt.Errorf("error loading frozen countries: %w", err)
+	}
+
+	cacheInstance.SetFrozenCountries(frozen)
+	return nil
+}
+
+// runMigrateCommand handles `server migrate up|down [steps]|status`
+func runMigrateCommand(cfg *config.Config, args []string) error {
+	db, err := cli.OpenDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return cli.Migrate(db, args, os.Stdout)
+}
+
+// openStore opens the storage backend selected by DATABASE_DRIVER
+func openStore(cfg *config.Config) (database.Store, error) {
+	switch cfg.DatabaseDriver {
+	case settings.DriverSQLite:
+		if err := database.InitDatabase(cfg.DatabasePath, cfg.MigrationsDir); err != nil {
+			return nil, err
+		}
+		return database.NewSQLiteStore(database.GetDB()), nil
+	case settings.DriverPostgres:
+		return database.OpenPostgresStore(cfg.DatabaseURL, cfg.MigrationsDir, cli.PoolOptions(cfg))
+	case settings.DriverMemory:
+		slog.Warn("Using in-memory store, counts will not survive a restart")
+		return database.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q (expected sqlite, postgres or memory)", cfg.DatabaseDriver)
+	}
+}
+
+// openLogFile opens the rotating log file with the environment's size and retention limits
+func openLogFile(cfg *config.Config) (*logging.FileWriter, error) {
+	interval, err := settings.ParseLogRotation(cfg.LogRotation)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := logging.FileOptionsForEnvironment(cfg.Environment)
+	opts.Dir = cfg.LogDir
+	opts.Filename = "app.log"
+	opts.Interval = interval
+	opts.Compress = cfg.LogCompress
+
+	return logging.NewFileWriter(opts)
+}
+
+// newHealthChecker registers the readiness checks: the store, the flusher, the cache and,
+// for SQLite, free disk space in the database directory
+func newHealthChecker(cfg *config.Config, store database.Store, cacheInstance *cache.Cache, bgProcessor *processor.BackgroundProcessor) *health.Checker {
+	checker := health.NewChecker(cfg.ReadyCheckTimeout)
+	checker.Add("database", health.PingCheck(store))
+	checker.Add("flush", health.FlushCheck(bgProcessor.FlushStatus, cfg.ReadyMaxFlushAge))
+	checker.Add("cache", health.CacheCheck(cacheInstance))
+	if cfg.DatabaseDriver == settings.DriverSQLite {
+		checker.Add("disk", health.DiskCheck(filepath.Dir(cfg.DatabasePath), uint64(cfg.ReadyMinFreeDisk)*1024*1024))
+	}
+	return checker
+}
+
+// openJournal opens the click journal, or returns nil when it is disabled
+func openJournal(cfg *config.Config) (*journal.Journal, error) {
+	if cfg.JournalPath == "" {
+		return nil, nil
+	}
+
+	durability, err := settings.ParseJournalDurability(cfg.JournalDurability)
+	if err != nil {
+		return nil, err
+	}
+
+	return journal.Open(cfg.JournalPath, durability)
+}
+
+// newAdminApp creates the admin app serving Prometheus metrics and, when credentials
+// are configured, the admin API
+func newAdminApp(authenticator *auth.Authenticator, adminHandler *handlers.AdminHandler) *fiber.App {
+	adminApp := fiber.New(fiber.Config{
+		AppName:               "ClickFlag Admin",
+		DisableStartupMessage: true,
+	})
+
+	adminApp.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
+	if !authenticator.Enabled() {
+		slog.Info("Admin API disabled, set ADMIN_API_KEYS or ADMIN_TOKEN_SECRET to enable it")
+		return adminApp
+	}
+
+	admin := adminApp.Group("/admin/v1", middleware.RequestID, middleware.RequestLogger, middleware.AdminAuth(authenticator))
+
+	admin.Get("/countries", adminHandler.ListCountries)
+	admin.Get("/countries/frozen", adminHandler.ListFrozen)
+	admin.Post("/countries/reset", adminHandler.ResetCounts)
+	admin.Put("/countries/:code", adminHandler.SetCountryValue)
+	admin.Post("/countries/:code/adjust", adminHandler.AdjustCountryValue)
+	admin.Post("/countries/:code/freeze", adminHandler.FreezeCountry)
+	admin.Delete("/countries/:code/freeze", adminHandler.UnfreezeCountry)
+	admin.Post("/flush", adminHandler.Flush)
+	admin.Post("/refresh", adminHandler.Refresh)
+	admin.Post("/config/reload", adminHandler.ReloadConfig)
+	admin.Get("/audit", adminHandler.AuditLog)
+
+	return adminApp
+}
+
+// publicReadRoutes are the GET routes whose CORS policy is CORS_READ_ORIGINS when set,
+// so other sites can show counts while clicks stay limited to CORS_ORIGINS
+var publicReadRoutes = []string{
+	"/api/v1/stream",
+	"/api/v1/countries",
+	"/api/v1/countries/history",
+	"/api/v1/countries/:code/history",
+}
+
+// corsPolicy returns the CORS policy of the configuration
+func corsPolicy(cfg *config.Config) middleware.CORSPolicy {
+	return middleware.CORSPolicy{
+		Origins:       cfg.CORSOrigins,
+		Methods:       cfg.CORSMethods,
+		Headers:       cfg.CORSHeaders,
+		ExposeHeaders: cfg.CORSExposeHeaders,
+		Credentials:   cfg.CORSCredentials,
+		MaxAge:        cfg.CORSMaxAge,
+	}
+}
+
+// clickRules returns the click limits per IP and per IP and country
+func clickRules(cfg *config.Config) (ratelimit.Rule, ratelimit.Rule) {
+	return ratelimit.Rule{Rate: float64(cfg.ClickRate), Burst: cfg.ClickBurst},
+		ratelimit.Rule{Rate: float64(cfg.ClickCountryRate), Burst: cfg.ClickCountryBurst}
+}
+
+// readRule returns the limit of GET requests per IP
+func readRule(cfg *config.Config) ratelimit.Rule {
+	return ratelimit.Rule{Rate: float64(cfg.ReadRate), Burst: cfg.ReadBurst}
 }
 
 // setupRoutes sets up all application routes
-func setupRoutes(app *fiber.App, countryHandler *handlers.CountryHandler) {
+func setupRoutes(app *fiber.App, readLimiter *ratelimit.Limiter, countryHandler *handlers.CountryHandler, healthHandler *handlers.HealthHandler, batchHandler *handlers.BatchHandler, historyHandler *handlers.HistoryHandler, streamHandler *handlers.StreamHandler, wsHandler *handlers.WebSocketHandler) {
 	// Health check endpoint
-	app.Get("/health", middleware.HealthCheckMiddleware, countryHandler.HealthCheck)
+	app.Get("/health", middleware.HealthCheckMiddleware, healthHandler.HealthCheck)
+
+	// Probes: liveness only checks the process, readiness checks its dependencies
+	app.Get("/livez", middleware.HealthCheckMiddleware, healthHandler.Livez)
+	app.Get("/readyz", middleware.HealthCheckMiddleware, healthHandler.Readyz)
+
+	// API routes; reads are limited per IP, clicks by their handlers
+	api := app.Group("/api/v1", middleware.ReadRateLimit(readLimiter))
+
+	// Live count stream (Server-Sent Events)
+	api.Get("/stream", streamHandler.Stream)
 
-	// API routes
-	api := app.Group("/api/v1")
+	// Clicks and live count deltas over WebSocket
+	api.Get("/ws", wsHandler.Upgrade, wsHandler.Handler())
 
 	// Country routes
 	countries := api.Group("/countries")
 	countries.Get("/", countryHandler.GetCountries)
 	countries.Post("/", countryHandler.AddCountry)
+	countries.Post("/batch", batchHandler.AddCountriesBatch)
+	countries.Get("/history", historyHandler.GetHistory)
+	countries.Get("/:code/history", historyHandler.GetCountryHistory)
 
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -121,8 +548,15 @@ func setupRoutes(app *fiber.App, countryHandler *handlers.CountryHandler) {
 			"version": "1.0.0",
 			"endpoints": fiber.Map{
 				"health":      "/health",
+				"livez":       "/livez",
+				"readyz":      "/readyz",
 				"countries":   "/api/v1/countries",
 				"add_country": "/api/v1/countries (POST)",
+				"add_batch":   "/api/v1/countries/batch (POST)",
+				"history":     "/api/v1/countries/:code/history",
+				"compare":     "/api/v1/countries/history?codes=TR,US",
+				"stream":      "/api/v1/stream",
+				"websocket":   "/api/v1/ws",
 			},
 		})
 	})
@@ -1,45 +1,163 @@
 package config
 
 import (
-	"log"
-	"os"
+	"time"
 
-	"github.com/joho/godotenv"
+	"clickflag-go-backend/settings"
 )
 
-// Config holds application configuration
+// Config holds application configuration. Every field can be set in the config file
+// (by its key), in the environment (by its env name) and on the command line (-key,
+// with dashes instead of underscores). Fields tagged reload:"true" are applied by
+// Reloader without a restart.
 type Config struct {
-	Port         string
-	DatabasePath string
-	LogLevel     string
-	Environment  string
-}
+	// Server
+	Port            int           `key:"port" env:"PORT" help:"public HTTP port"`
+	AdminPort       int           `key:"admin_port" env:"ADMIN_PORT" help:"port of /metrics and /admin/v1; 0 disables the admin server"`
+	Environment     string        `key:"environment" env:"ENVIRONMENT" help:"development, staging or production"`
+	ReadTimeout     time.Duration `key:"read_timeout" env:"READ_TIMEOUT" help:"longest time to read a request"`
+	WriteTimeout    time.Duration `key:"write_timeout" env:"WRITE_TIMEOUT" help:"longest time to write a response"`
+	IdleTimeout     time.Duration `key:"idle_timeout" env:"IDLE_TIMEOUT" help:"how long idle keep-alive connections stay open"`
+	RequestTimeout  time.Duration `key:"request_timeout" env:"REQUEST_TIMEOUT" help:"deadline of the context handlers work with"`
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" help:"time to drain requests, and then to flush pending clicks, on shutdown"`
+	TrustedProxies  []string      `key:"trusted_proxies" env:"TRUSTED_PROXIES" help:"proxies, as IPs or CIDR ranges, whose client IP header is believed"`
+	ClientIPHeader  string        `key:"client_ip_header" env:"CLIENT_IP_HEADER" help:"where trusted proxies put the client IP: x-forwarded-for, x-real-ip, forwarded or proxy-protocol"`
 
-// Load loads configuration from environment variables
-func Load() *Config {
-	// Load .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("No .env file found, using system environment variables")
-	}
+	// Rate limits
+	ClickRate         int `key:"click_rate" env:"CLICK_RATE" reload:"true" help:"clicks per second per IP on POST /api/v1/countries"`
+	ClickBurst        int `key:"click_burst" env:"CLICK_BURST" reload:"true" help:"clicks one IP may send in a burst"`
+	ClickCountryRate  int `key:"click_country_rate" env:"CLICK_COUNTRY_RATE" reload:"true" help:"clicks per second per IP for one country"`
+	ClickCountryBurst int `key:"click_country_burst" env:"CLICK_COUNTRY_BURST" reload:"true" help:"clicks one IP may send for one country in a burst"`
+	ReadRate          int `key:"read_rate" env:"READ_RATE" reload:"true" help:"GET requests per second per IP under /api/v1"`
+	ReadBurst         int `key:"read_burst" env:"READ_BURST" reload:"true" help:"GET requests one IP may send in a burst"`
+	RateLimitMaxKeys  int `key:"rate_limit_max_keys" env:"RATE_LIMIT_MAX_KEYS" reload:"true" help:"clients each rate limiter tracks before evicting the least recently seen"`
 
-	config := &Config{
-		Port:         getEnv("PORT", "8080"),
-		DatabasePath: getEnv("DATABASE_PATH", "./data/countries.db"),
-		LogLevel:     getEnv("LOG_LEVEL", "info"),
-		Environment:  getEnv("ENVIRONMENT", "development"),
-	}
+	// CORS
+	CORSOrigins       []string      `key:"cors_origins" env:"CORS_ORIGINS" reload:"true" help:"allowed CORS origins, e.g. https://example.com or https://*.example.com; required in staging and production; empty allows localhost in development"`
+	CORSReadOrigins   []string      `key:"cors_read_origins" env:"CORS_READ_ORIGINS" reload:"true" help:"origins allowed on the public read endpoints, e.g. *; empty uses cors_origins"`
+	CORSMethods       []string      `key:"cors_methods" env:"CORS_METHODS" reload:"true" help:"methods allowed in cross-origin requests"`
+	CORSHeaders       []string      `key:"cors_headers" env:"CORS_HEADERS" reload:"true" help:"request headers allowed in cross-origin requests"`
+	CORSExposeHeaders []string      `key:"cors_expose_headers" env:"CORS_EXPOSE_HEADERS" reload:"true" help:"response headers readable by cross-origin scripts"`
+	CORSCredentials   bool          `key:"cors_credentials" env:"CORS_CREDENTIALS" reload:"true" help:"allow cookies and credentials in cross-origin requests to cors_origins"`
+	CORSMaxAge        time.Duration `key:"cors_max_age" env:"CORS_MAX_AGE" reload:"true" help:"how long browsers may cache preflight responses; 0 omits the header"`
+
+	// Admin API
+	AdminAPIKeys     string `key:"admin_api_keys" env:"ADMIN_API_KEYS" secret:"true" help:"admin API keys as actor:key,actor:key"`
+	AdminTokenSecret string `key:"admin_token_secret" env:"ADMIN_TOKEN_SECRET" secret:"true" help:"HMAC secret of signed admin tokens; empty disables them"`
+
+	// Database
+	DatabaseDriver    string        `key:"database_driver" env:"DATABASE_DRIVER" help:"sqlite, postgres or memory"`
+	DatabasePath      string        `key:"database_path" env:"DATABASE_PATH" help:"SQLite database file"`
+	DatabaseURL       string        `key:"database_url" env:"DATABASE_URL" secret:"true" help:"PostgreSQL connection string"`
+	MigrationsDir     string        `key:"migrations_dir" env:"MIGRATIONS_DIR" help:"read migrations from this directory instead of the embedded copy"`
+	DBMaxOpenConns    int           `key:"db_max_open_conns" env:"DB_MAX_OPEN_CONNS" help:"PostgreSQL pool size (SQLite always uses one connection)"`
+	DBMaxIdleConns    int           `key:"db_max_idle_conns" env:"DB_MAX_IDLE_CONNS" help:"idle PostgreSQL connections kept open"`
+	DBConnMaxLifetime time.Duration `key:"db_conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" help:"time after which a database connection is replaced"`
+
+	// Flushing and durability
+	FlushSchedule     string `key:"flush_schedule" env:"FLUSH_SCHEDULE" reload:"true" help:"cron expression (with seconds) of pending click flushes"`
+	RecoveryFilePath  string `key:"recovery_file_path" env:"RECOVERY_FILE_PATH" help:"where clicks that cannot be flushed on shutdown are written"`
+	JournalPath       string `key:"journal_path" env:"JOURNAL_PATH" help:"click journal file; empty disables the journal"`
+	JournalDurability string `key:"journal_durability" env:"JOURNAL_DURABILITY" help:"none, batch or request"`
+
+	// Click history
+	HistorySchedule  string        `key:"history_schedule" env:"HISTORY_SCHEDULE" help:"cron expression (with seconds) of history rollups and pruning"`
+	HistoryMinuteTTL time.Duration `key:"history_minute_retention" env:"HISTORY_MINUTE_RETENTION" help:"retention of per-minute click history; 0 keeps it forever"`
+	HistoryHourTTL   time.Duration `key:"history_hour_retention" env:"HISTORY_HOUR_RETENTION" help:"retention of hourly click history; 0 keeps it forever"`
+	HistoryDayTTL    time.Duration `key:"history_day_retention" env:"HISTORY_DAY_RETENTION" help:"retention of daily click history; 0 keeps it forever"`
+
+	// Live streams and clicks
+	StreamMaxClients  int           `key:"stream_max_subscribers" env:"STREAM_MAX_SUBSCRIBERS" help:"cap on concurrent live stream subscribers; 0 is unlimited"`
+	StreamHeartbeat   time.Duration `key:"stream_heartbeat" env:"STREAM_HEARTBEAT" help:"interval of keep-alive messages on idle streams"`
+	WSClickRate       int           `key:"ws_click_rate" env:"WS_CLICK_RATE" help:"clicks per second allowed on one WebSocket connection"`
+	WSClickBurst      int           `key:"ws_click_burst" env:"WS_CLICK_BURST" help:"clicks one WebSocket connection may send in a burst"`
+	BatchClientRate   int           `key:"batch_client_rate" env:"BATCH_CLIENT_RATE" help:"plausible clicks per second per client in batches"`
+	BatchCountryRate  int           `key:"batch_country_rate" env:"BATCH_COUNTRY_RATE" help:"plausible clicks per second per client for one country in batches"`
+	BatchWindow       time.Duration `key:"batch_window" env:"BATCH_WINDOW" help:"longest client-side window one batch may cover"`
+	CompressResponses bool          `key:"response_compression" env:"RESPONSE_COMPRESSION" help:"pre-compress the country list with gzip and brotli on each refresh"`
+
+	// Readiness
+	ReadyCheckTimeout time.Duration `key:"ready_check_timeout" env:"READY_CHECK_TIMEOUT" help:"timeout of each readiness check"`
+	ReadyMaxFlushAge  time.Duration `key:"ready_max_flush_age" env:"READY_MAX_FLUSH_AGE" help:"/readyz fails when no flush succeeded for this long"`
+	ReadyMinFreeDisk  int           `key:"ready_min_free_disk_mb" env:"READY_MIN_FREE_DISK_MB" help:"/readyz fails below this many MB free in the database directory"`
 
-	return config
+	// Logging and tracing
+	LogLevel         string  `key:"log_level" env:"LOG_LEVEL" reload:"true" help:"debug, info, warn, error or critical"`
+	LogDir           string  `key:"log_dir" env:"LOG_DIR" help:"directory of the rotating log files"`
+	LogRotation      string  `key:"log_rotation" env:"LOG_ROTATION" help:"time-based log rotation: hourly, daily or none"`
+	LogCompress      bool    `key:"log_compress" env:"LOG_COMPRESS" help:"gzip rotated log files"`
+	TraceExporter    string  `key:"trace_exporter" env:"TRACE_EXPORTER" help:"otlp, stdout or none"`
+	TraceEndpoint    string  `key:"trace_endpoint" env:"TRACE_ENDPOINT" help:"OTLP/HTTP collector address"`
+	TraceInsecure    bool    `key:"trace_insecure" env:"TRACE_INSECURE" help:"send OTLP over plain HTTP"`
+	TraceSampleRatio float64 `key:"trace_sample_ratio" env:"TRACE_SAMPLE_RATIO" help:"fraction of new traces that are sampled"`
 }
 
-// getEnv gets an environment variable with a fallback value
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Default returns the configuration used for every setting that is not configured
+func Default() *Config {
+	return &Config{
+		Port:            8080,
+		AdminPort:       9090,
+		Environment:     "development",
+		ReadTimeout:     3 * time.Second,
+		WriteTimeout:    3 * time.Second,
+		IdleTimeout:     3 * time.Second,
+		RequestTimeout:  5 * time.Second,
+		ShutdownTimeout: 5 * time.Second,
+		ClientIPHeader:  settings.ClientIPXForwardedFor,
+
+		ClickRate:         20,
+		ClickBurst:        40,
+		ClickCountryRate:  10,
+		ClickCountryBurst: 20,
+		ReadRate:          20,
+		ReadBurst:         40,
+		RateLimitMaxKeys:  100000,
+
+		CORSMethods:       []string{"GET", "POST", "OPTIONS"},
+		CORSHeaders:       []string{"Origin", "Content-Type", "Accept", "X-Request-ID", "traceparent", "tracestate"},
+		CORSExposeHeaders: []string{"X-Request-ID"},
+
+		DatabaseDriver:    settings.DriverSQLite,
+		DatabasePath:      "./data/countries.db",
+		DBMaxOpenConns:    10,
+		DBMaxIdleConns:    5,
+		DBConnMaxLifetime: time.Hour,
+
+		FlushSchedule:     "*/5 * * * * *",
+		RecoveryFilePath:  "./data/pending_recovery.json",
+		JournalDurability: "batch",
+
+		HistorySchedule:  "30 * * * * *",
+		HistoryMinuteTTL: 48 * time.Hour,
+		HistoryHourTTL:   90 * 24 * time.Hour,
+
+		StreamMaxClients:  1000,
+		StreamHeartbeat:   15 * time.Second,
+		WSClickRate:       10,
+		WSClickBurst:      20,
+		BatchClientRate:   30,
+		BatchCountryRate:  20,
+		BatchWindow:       10 * time.Second,
+		CompressResponses: true,
+
+		ReadyCheckTimeout: 2 * time.Second,
+		ReadyMaxFlushAge:  30 * time.Second,
+		ReadyMinFreeDisk:  100,
+
+		LogLevel:         "info",
+		LogDir:           "logs",
+		LogRotation:      "daily",
+		LogCompress:      true,
+		TraceExporter:    "none",
+		TraceEndpoint:    "localhost:4318",
+		TraceInsecure:    true,
+		TraceSampleRatio: 1,
 	}
-	return fallback
 }
 
+// developmentCORSOrigins are the allowed origins in development when CORS_ORIGINS is empty
+var developmentCORSOrigins = []string{"http://localhost:3000", "http://localhost:8080", "http://127.0.0.1:3000"}
+
 // IsDevelopment checks if the application is running in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
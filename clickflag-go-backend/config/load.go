@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable that selects the config file when -config is not given
+const FileEnv = "CONFIG_FILE"
+
+// Error lists every invalid setting found while loading the configuration
+type Error struct {
+	Problems []string
+}
+
+// Error formats one problem per line
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// setting is one configurable field
+type setting struct {
+	key    string
+	env    string
+	help   string
+	reload bool // Applied at runtime by Reloader
+	secret bool // Never shown in reload reports
+	value  reflect.Value
+}
+
+// Load builds the configuration in layers: defaults, then the YAML or TOML config file
+// (-config or CONFIG_FILE), then the environment (a .env file fills in variables that are
+// not set, and is read again on every Load), then command-line flags. It returns the arguments left after the flags, and
+// an *Error listing every invalid setting, or flag.ErrHelp if -h was given.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	settings := settingsOf(cfg)
+	var problems []string
+
+	// Flags are parsed first, so -config can select the file, but applied last
+	flagValues := make(map[string]string)
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configPath := flags.String("config", "", "YAML or TOML config file (env "+FileEnv+")")
+	for _, s := range settings {
+		key := s.key
+		flags.Func(flagName(key), fmt.Sprintf("%s (env %s)", s.help, s.env), func(value string) error {
+			flagValues[key] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// A missing .env is fine; the environment is used as is
+	dotenv, err := godotenv.Read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		problems = append(problems, fmt.Sprintf(".env: %v", err))
+	}
+	getenv := func(name string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return dotenv[name]
+	}
+
+	if *configPath == "" {
+		*configPath = getenv(FileEnv)
+	}
+	if *configPath != "" {
+		problems = append(problems, applyFile(settings, *configPath)...)
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			problems = appendProblem(problems, "env "+s.env, setValue(s.value, value))
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := flagValues[s.key]; ok {
+			problems = appendProblem(problems, "flag -"+flagName(s.key), setValue(s.value, value))
+		}
+	}
+
+	if len(cfg.CORSOrigins) == 0 && cfg.IsDevelopment() {
+		cfg.CORSOrigins = developmentCORSOrigins
+	}
+
+	// Values that failed to parse are already reported, so validation runs only on clean input
+	if len(problems) == 0 {
+		problems = cfg.Validate()
+	}
+	if len(problems) > 0 {
+		return nil, nil, &Error{Problems: problems}
+	}
+
+	return cfg, flags.Args(), nil
+}
+
+// applyFile sets every key of a YAML or TOML file; unknown keys are reported so typos do not go unnoticed
+func applyFile(settings []setting, path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("config file: %v", err)}
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		return []string{fmt.Sprintf("config file %s: unknown format (expected .yaml, .yml or .toml)", path)}
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("config file %s: %v", path, err)}
+	}
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var problems []string
+	for _, key := range sortedKeys(values) {
+		source := fmt.Sprintf("%s: %s", filepath.Base(path), key)
+		s, ok := byKey[key]
+		if !ok {
+			problems = append(problems, source+": unknown setting")
+			continue
+		}
+
+		value, err := fileValue(values[key])
+		if err == nil {
+			err = setValue(s.value, value)
+		}
+		problems = appendProblem(problems, source, err)
+	}
+	return problems
+}
+
+// fileValue converts a decoded file value into the text form used by the environment
+func fileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case []any, map[string]any:
+				return "", errors.New("lists may only contain plain values")
+			}
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", errors.New("nested sections are not supported")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// setValue parses text into a field of a supported type
+func setValue(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (e.g. 500ms, 5s, 48h)", value)
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(number))
+	case reflect.Float64:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(number)
+	case reflect.Bool:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q (expected true or false)", value)
+		}
+		field.SetBool(enabled)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// settingsOf returns the configurable fields of cfg
+func settingsOf(cfg *Config) []setting {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	settings := make([]setting, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		settings = append(settings, setting{
+			key:    field.Tag.Get("key"),
+			env:    field.Tag.Get("env"),
+			help:   field.Tag.Get("help"),
+			reload: field.Tag.Get("reload") == "true",
+			secret: field.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return settings
+}
+
+// flagName returns the command-line flag of a key
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// appendProblem adds err, prefixed with where the value came from
+func appendProblem(problems []string, source string, err error) []string {
+	if err == nil {
+		return problems
+	}
+	return append(problems, fmt.Sprintf("%s: %v", source, err))
+}
+
+// sortedKeys returns the keys of a map in order, so problems are reported deterministically
+func sortedKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Change is a setting whose configured value differs from the running one
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// ReloadResult lists the settings a reload applied and those that only take effect after a
+// restart. When a hook fails, the runtime settings are rolled back instead of applied.
+type ReloadResult struct {
+	Applied         []Change `json:"applied"`
+	RolledBack      []Change `json:"rolled_back,omitempty"`
+	RestartRequired []Change `json:"restart_required"`
+}
+
+// String summarizes the result for logs and the audit log
+func (r *ReloadResult) String() string {
+	if len(r.Applied) == 0 && len(r.RolledBack) == 0 && len(r.RestartRequired) == 0 {
+		return "no changes"
+	}
+
+	var parts []string
+	if len(r.Applied) > 0 {
+		parts = append(parts, "applied "+joinChanges(r.Applied))
+	}
+	if len(r.RolledBack) > 0 {
+		parts = append(parts, "rolled back "+joinChanges(r.RolledBack))
+	}
+	if len(r.RestartRequired) > 0 {
+		parts = append(parts, "restart required for "+joinChanges(r.RestartRequired))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// joinChanges formats changes as "key (old -> new), ..."
+func joinChanges(changes []Change) string {
+	formatted := make([]string, len(changes))
+	for i, change := range changes {
+		formatted[i] = fmt.Sprintf("%s (%s -> %s)", change.Key, change.Old, change.New)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// ReloadHook applies the runtime settings of updated; old is the configuration it replaces.
+// After a failed reload it is called with the two swapped to restore the old settings.
+type ReloadHook func(old, updated *Config) error
+
+// Reloader re-reads the configuration with the arguments the process was started with
+// and applies the settings tagged reload:"true" through its hooks
+type Reloader struct {
+	mu      sync.Mutex
+	args    []string
+	current *Config
+	hooks   []ReloadHook
+}
+
+// NewReloader creates a reloader for the running configuration cfg, loaded from args
+func NewReloader(cfg *Config, args []string) *Reloader {
+	return &Reloader{args: args, current: cfg}
+}
+
+// OnReload registers a hook run on every successful reload
+func (r *Reloader) OnReload(hook ReloadHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// Current returns the running configuration; it must not be modified
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload loads the configuration again. An invalid configuration changes nothing.
+// Otherwise the runtime settings are swapped in and settings that need a restart
+// keep their running values and are reported. When a hook fails, the hooks that ran,
+// including the failed one, are run again with the running configuration, which is kept.
+func (r *Reloader) Reload() (*ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, _, err := Load(r.args)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *r.current
+	result := &ReloadResult{Applied: []Change{}, RestartRequired: []Change{}}
+
+	running := settingsOf(&updated)
+	for i, s := range settingsOf(loaded) {
+		target := running[i]
+		if reflect.DeepEqual(target.value.Interface(), s.value.Interface()) {
+			continue
+		}
+
+		change := Change{Key: s.key, Old: displayValue(target), New: displayValue(s)}
+		if !s.reload {
+			result.RestartRequired = append(result.RestartRequired, change)
+			continue
+		}
+		target.value.Set(s.value)
+		result.Applied = append(result.Applied, change)
+	}
+
+	for i, hook := range r.hooks {
+		if err := hook(r.current, &updated); err != nil {
+			result.Applied, result.RolledBack = []Change{}, result.Applied
+			return result, r.rollback(r.hooks[:i+1], &updated, fmt.Errorf("error applying configuration: %w", err))
+		}
+	}
+	r.current = &updated
+
+	return result, nil
+}
+
+// rollback restores the running configuration through hooks, in reverse order, after
+// applying updated failed with err
+func (r *Reloader) rollback(hooks []ReloadHook, updated *Config, err error) error {
+	errs := []error{err}
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if rollbackErr := hooks[i](updated, r.current); rollbackErr != nil {
+			errs = append(errs, fmt.Errorf("error restoring the running configuration: %w", rollbackErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// displayValue formats a setting for reload reports, hiding secrets
+func displayValue(s setting) string {
+	if s.secret {
+		return "(hidden)"
+	}
+	if items, ok := s.value.Interface().([]string); ok {
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(s.value.Interface())
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"clickflag-go-backend/settings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleParser parses the cron expressions of the background jobs, which include seconds
+var scheduleParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Validate returns every invalid setting, named by its config key
+func (c *Config) Validate() []string {
+	v := &validator{}
+
+	v.port("port", c.Port, false)
+	v.port("admin_port", c.AdminPort, true)
+	v.check(c.AdminPort == 0 || c.AdminPort != c.Port, "admin_port", "must differ from port %d", c.Port)
+	v.oneOf("environment", c.Environment, "development", "staging", "production")
+	v.positive("read_timeout", c.ReadTimeout)
+	v.positive("write_timeout", c.WriteTimeout)
+	v.positive("idle_timeout", c.IdleTimeout)
+	v.positive("request_timeout", c.RequestTimeout)
+	v.positive("shutdown_timeout", c.ShutdownTimeout)
+	for _, proxy := range c.TrustedProxies {
+		if _, err := settings.ParseTrustedProxy(proxy); err != nil {
+			v.add("trusted_proxies", "%v", err)
+		}
+	}
+
+	v.oneOf("client_ip_header", c.ClientIPHeader, settings.ClientIPSources...)
+	v.check(c.ClientIPHeader != settings.ClientIPProxyProtocol || len(c.TrustedProxies) > 0, "client_ip_header", "proxy-protocol requires trusted_proxies")
+
+	v.check(c.ClickRate > 0, "click_rate", "must be positive, got %d", c.ClickRate)
+	v.check(c.ClickBurst >= c.ClickRate, "click_burst", "must be at least click_rate (%d), got %d", c.ClickRate, c.ClickBurst)
+	v.check(c.ClickCountryRate > 0 && c.ClickCountryRate <= c.ClickRate, "click_country_rate", "must be between 1 and click_rate (%d), got %d", c.ClickRate, c.ClickCountryRate)
+	v.check(c.ClickCountryBurst >= c.ClickCountryRate, "click_country_burst", "must be at least click_country_rate (%d), got %d", c.ClickCountryRate, c.ClickCountryBurst)
+	v.check(c.ReadRate > 0, "read_rate", "must be positive, got %d", c.ReadRate)
+	v.check(c.ReadBurst >= c.ReadRate, "read_burst", "must be at least read_rate (%d), got %d", c.ReadRate, c.ReadBurst)
+	v.check(c.RateLimitMaxKeys > 0, "rate_limit_max_keys", "must be positive, got %d", c.RateLimitMaxKeys)
+
+	v.origins("cors_origins", c.CORSOrigins)
+	if c.Environment == "staging" || c.IsProduction() {
+		v.check(len(c.CORSOrigins) > 0, "cors_origins", "is required in %s, e.g. https://clickflag.com; the browser app cannot call the API without it", c.Environment)
+	}
+	v.origins("cors_read_origins", c.CORSReadOrigins)
+	v.check(!c.CORSCredentials || !contains(c.CORSOrigins, "*"), "cors_credentials", "cannot be used with the * origin")
+	for _, method := range c.CORSMethods {
+		v.check(httpMethods[method], "cors_methods", "unknown method %q", method)
+	}
+	for _, header := range c.CORSHeaders {
+		v.check(isHeaderName(header), "cors_headers", "invalid header name %q", header)
+	}
+	for _, header := range c.CORSExposeHeaders {
+		v.check(isHeaderName(header), "cors_expose_headers", "invalid header name %q", header)
+	}
+	v.notNegative("cors_max_age", c.CORSMaxAge)
+
+	if _, err := settings.ParseAPIKeys(c.AdminAPIKeys); err != nil {
+		v.add("admin_api_keys", "%v", err)
+	}
+	v.check(c.AdminTokenSecret == "" || len(c.AdminTokenSecret) >= 32, "admin_token_secret", "must be at least 32 characters")
+
+	v.oneOf("database_driver", c.DatabaseDriver, settings.DriverSQLite, settings.DriverPostgres, settings.DriverMemory)
+	v.check(c.DatabaseDriver != settings.DriverSQLite || c.DatabasePath != "", "database_path", "is required for the sqlite driver")
+	v.check(c.DatabaseDriver != settings.DriverPostgres || c.DatabaseURL != "", "database_url", "is required for the postgres driver")
+	v.check(c.DBMaxOpenConns > 0, "db_max_open_conns", "must be positive, got %d", c.DBMaxOpenConns)
+	v.check(c.DBMaxIdleConns >= 0 && c.DBMaxIdleConns <= c.DBMaxOpenConns, "db_max_idle_conns", "must be between 0 and db_max_open_conns (%d), got %d", c.DBMaxOpenConns, c.DBMaxIdleConns)
+	v.positive("db_conn_max_lifetime", c.DBConnMaxLifetime)
+
+	if flush := v.schedule("flush_schedule", c.FlushSchedule); flush != nil && c.ReadyMaxFlushAge > 0 {
+		interval := scheduleInterval(flush)
+		v.check(interval < c.ReadyMaxFlushAge, "flush_schedule", "runs every %s, which must be shorter than ready_max_flush_age (%s) or /readyz fails between flushes", interval, c.ReadyMaxFlushAge)
+	}
+	v.check(c.RecoveryFilePath != "", "recovery_file_path", "is required")
+	if _, err := settings.ParseJournalDurability(c.JournalDurability); err != nil {
+		v.add("journal_durability", "%v", err)
+	}
+
+	v.schedule("history_schedule", c.HistorySchedule)
+	v.notNegative("history_minute_retention", c.HistoryMinuteTTL)
+	v.notNegative("history_hour_retention", c.HistoryHourTTL)
+	v.notNegative("history_day_retention", c.HistoryDayTTL)
+
+	v.check(c.StreamMaxClients >= 0, "stream_max_subscribers", "must not be negative, got %d", c.StreamMaxClients)
+	v.positive("stream_heartbeat", c.StreamHeartbeat)
+	v.check(c.WSClickRate > 0, "ws_click_rate", "must be positive, got %d", c.WSClickRate)
+	v.check(c.WSClickBurst >= c.WSClickRate, "ws_click_burst", "must be at least ws_click_rate (%d), got %d", c.WSClickRate, c.WSClickBurst)
+	v.check(c.BatchClientRate > 0, "batch_client_rate", "must be positive, got %d", c.BatchClientRate)
+	v.check(c.BatchCountryRate > 0 && c.BatchCountryRate <= c.BatchClientRate, "batch_country_rate", "must be between 1 and batch_client_rate (%d), got %d", c.BatchClientRate, c.BatchCountryRate)
+	v.positive("batch_window", c.BatchWindow)
+
+	v.positive("ready_check_timeout", c.ReadyCheckTimeout)
+	v.positive("ready_max_flush_age", c.ReadyMaxFlushAge)
+	v.check(c.ReadyMinFreeDisk >= 0, "ready_min_free_disk_mb", "must not be negative, got %d", c.ReadyMinFreeDisk)
+
+	if _, err := settings.ParseLogLevel(c.LogLevel); err != nil {
+		v.add("log_level", "%v", err)
+	}
+	v.check(c.LogDir != "", "log_dir", "is required")
+	if _, err := settings.ParseLogRotation(c.LogRotation); err != nil {
+		v.add("log_rotation", "%v", err)
+	}
+	v.oneOf("trace_exporter", c.TraceExporter, "none", "otlp", "stdout")
+	v.check(c.TraceSampleRatio >= 0 && c.TraceSampleRatio <= 1, "trace_sample_ratio", "must be between 0 and 1, got %g", c.TraceSampleRatio)
+
+	return v.problems
+}
+
+// validator collects problems
+type validator struct {
+	problems []string
+}
+
+// add records a problem with a setting
+func (v *validator) add(key, format string, args ...any) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+// check records a problem unless ok
+func (v *validator) check(ok bool, key, format string, args ...any) {
+	if !ok {
+		v.add(key, format, args...)
+	}
+}
+
+// port checks a TCP port; zero is allowed only where it disables a listener
+func (v *validator) port(key string, port int, zeroDisables bool) {
+	min := 1
+	if zeroDisables {
+		min = 0
+	}
+	v.check(port >= min && port <= 65535, key, "must be a port between %d and 65535, got %d", min, port)
+}
+
+// positive checks that a duration is greater than zero
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, key, "must be positive, got %s", d)
+}
+
+// notNegative checks that a duration is zero or more
+func (v *validator) notNegative(key string, d time.Duration) {
+	v.check(d >= 0, key, "must not be negative, got %s", d)
+}
+
+// oneOf checks that a value is one of the allowed values
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(key, "must be one of %v, got %q", allowed, value)
+}
+
+// schedule checks a cron expression with seconds, e.g. "*/5 * * * * *" or "@every 5s",
+// and returns it when it is valid
+func (v *validator) schedule(key, expression string) cron.Schedule {
+	schedule, err := scheduleParser.Parse(expression)
+	if err != nil {
+		v.add(key, "invalid cron expression %q: %v", expression, err)
+		return nil
+	}
+	return schedule
+}
+
+// scheduleInterval returns the longest gap between the next runs of a schedule. Cron
+// expressions need not run at even intervals, so up to a week of runs is sampled.
+func scheduleInterval(schedule cron.Schedule) time.Duration {
+	const samples = 1000
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	longest := time.Duration(0)
+	previous := schedule.Next(start)
+	for i := 0; i < samples && !previous.IsZero() && previous.Sub(start) < 7*24*time.Hour; i++ {
+		next := schedule.Next(previous)
+		if next.IsZero() {
+			break
+		}
+		longest = max(longest, next.Sub(previous))
+		previous = next
+	}
+	return longest
+}
+
+// origins checks CORS origins: "*" on its own, or scheme://host[:port] where the host
+// may start with "*." to allow every subdomain
+func (v *validator) origins(key string, origins []string) {
+	for _, origin := range origins {
+		if origin == "*" {
+			v.check(len(origins) == 1, key, "* must be the only origin")
+			continue
+		}
+
+		// A subdomain wildcard is only allowed as the first label
+		host := strings.Replace(origin, "://*.", "://", 1)
+		parsed, err := url.Parse(host)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.Contains(parsed.Host, "*") ||
+			(parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" {
+			v.add(key, "invalid origin %q (expected scheme://host[:port] or scheme://*.host[:port])", origin)
+		}
+	}
+}
+
+// httpMethods are the methods accepted in cors_methods
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// isHeaderName checks that name is a valid HTTP header name
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,13 +3,13 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
-	"clickflag-go-backend/models"
+	"clickflag-go-backend/migrations"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -19,142 +19,92 @@ var (
 	once sync.Once
 )
 
-// InitDatabase initializes the database connection and runs migrations
-func InitDatabase(dbPath string) error {
+// InitDatabase initializes the database connection and runs migrations.
+// Migrations embedded in the binary are used unless migrationsDir is set.
+func InitDatabase(dbPath string, migrationsDir string) error {
 	var err error
 	once.Do(func() {
-		// Ensure directory exists
-		dir := filepath.Dir(dbPath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			log.Fatalf("Error creating database directory: %v", err)
-		}
-
-		// Open database connection
-		db, err = sql.Open("sqlite3", dbPath)
-		if err != nil {
-			log.Fatalf("Error opening database: %v", err)
-		}
-
-		// Set connection pool settings
-		db.SetMaxOpenConns(1) // SQLite only supports one writer at a time
-		db.SetMaxIdleConns(1)
-		db.SetConnMaxLifetime(time.Hour)
-
-		// Test connection
-		if err := db.Ping(); err != nil {
-			log.Fatalf("Error pinging database: %v", err)
+		if err = Connect(dbPath); err != nil {
+			return
 		}
 
 		// Run migrations
-		if err := runMigrations(); err != nil {
-			log.Fatalf("Error running migrations: %v", err)
+		if err = runMigrations(migrationsDir); err != nil {
+			err = fmt.Errorf("error running migrations: %w", err)
+			return
 		}
 
-		log.Println("Database initialized successfully")
+		slog.Info("Database initialized successfully", "path", dbPath)
 	})
 
 	return err
 }
 
-// GetDB returns the database instance
-func GetDB() *sql.DB {
-	return db
-}
-
-// CloseDatabase closes the database connection
-func CloseDatabase() error {
-	if db != nil {
-		return db.Close()
-	}
-	return nil
-}
-
-// runMigrations executes database migrations
-func runMigrations() error {
-	// Read migration file from migrations directory
-	migrationPath := "migrations/001_create_countries_table.sql"
-	migrationSQL, err := os.ReadFile(migrationPath)
-	if err != nil {
-		log.Fatalf("Error reading migration file %s: %v", migrationPath, err)
+// Connect opens the database connection without running migrations
+func Connect(dbPath string) error {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating database directory: %w", err)
 	}
 
-	migrationSecondPath := "migrations/002_replace_tw_with_ss.sql"
-	migrationSecondSQL, err := os.ReadFile(migrationSecondPath)
+	// Open database connection
+	conn, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
-		log.Fatalf("Error reading migration file %s: %v", migrationSecondPath, err)
+		return fmt.Errorf("error opening database: %w", err)
 	}
 
+	// Set connection pool settings
+	conn.SetMaxOpenConns(1) // SQLite only supports one writer at a time
+	conn.SetMaxIdleConns(1)
+	conn.SetConnMaxLifetime(time.Hour)
 
-	// Execute migration
-	_, err = db.Exec(string(migrationSQL))
-	if err != nil {
-		log.Fatalf("Error executing migration: %v", err)
+	// Test connection
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return fmt.Errorf("error pinging database: %w", err)
 	}
 
-	_, err = db.Exec(string(migrationSecondSQL))
-	if err != nil {
-		log.Fatalf("Error executing migration: %v", err)
-	}
-
-	log.Println("Database migrations completed successfully")
+	db = conn
 	return nil
 }
 
-// GetAllCountries retrieves all countries from the database
-func GetAllCountries() ([]models.Country, error) {
-	query := `
-		SELECT id, country_code, value
-		FROM countries
-	`
+// GetDB returns the database instance
+func GetDB() *sql.DB {
+	return db
+}
 
-	rows, err := db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("error querying countries: %w", err)
-	}
-	defer rows.Close()
-
-	var countries []models.Country
-	for rows.Next() {
-		var country models.Country
-		err := rows.Scan(
-			&country.ID,
-			&country.CountryCode,
-			&country.Value,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning country: %w", err)
-		}
-		countries = append(countries, country)
+// CloseDatabase closes the database connection
+func CloseDatabase() error {
+	if db != nil {
+		return db.Close()
 	}
+	return nil
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating countries: %w", err)
+// NewDefaultMigrator returns a migrator for the embedded migrations,
+// or for the files in migrationsDir when an override directory is set
+func NewDefaultMigrator(migrationsDir string) (*Migrator, error) {
+	if migrationsDir != "" {
+		slog.Info("Using migrations from override directory", "dir", migrationsDir)
+		return NewMigrator(db, os.DirFS(migrationsDir))
 	}
 
-	return countries, nil
+	return NewMigrator(db, migrations.FS)
 }
 
-// IncrementCountryValueBy increments the value for a specific country code by given amount
-func IncrementCountryValueBy(countryCode string, amount int32) error {
-	query := `
-		UPDATE countries 
-		SET value = value + ?
-		WHERE country_code = ?
-	`
-
-	result, err := db.Exec(query, amount, countryCode)
+// runMigrations applies pending database migrations
+func runMigrations(migrationsDir string) error {
+	migrator, err := NewDefaultMigrator(migrationsDir)
 	if err != nil {
-		return fmt.Errorf("error updating country value: %w", err)
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	applied, err := migrator.Up()
 	if err != nil {
-		return fmt.Errorf("error getting rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("country code %s not found", countryCode)
+		return err
 	}
 
+	slog.Info("Database migrations completed successfully", "applied", applied)
 	return nil
 }
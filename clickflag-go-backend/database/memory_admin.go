@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"clickflag-go-backend/models"
+)
+
+// SetCountryValue replaces a country's count and audits the change
+func (s *MemoryStore) SetCountryValue(ctx context.Context, countryCode string, value int, actor string) (models.AuditEntry, error) {
+	return s.updateCountryValue(countryCode, actor, models.AuditSetValue, func(before int) int {
+		return value
+	})
+}
+
+// AdjustCountryValue adds delta to a country's count and audits the change
+func (s *MemoryStore) AdjustCountryValue(ctx context.Context, countryCode string, delta int, actor string) (models.AuditEntry, error) {
+	return s.updateCountryValue(countryCode, actor, models.AuditAdjustValue, func(before int) int {
+		return before + delta
+	})
+}
+
+// updateCountryValue writes the value computed from a country's current count and audits the change
+func (s *MemoryStore) updateCountryValue(countryCode, actor, action string, compute func(before int) int) (models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	country, exists := s.countries[countryCode]
+	if !exists {
+		return models.AuditEntry{}, fmt.Errorf("%w: %s", ErrCountryNotFound, countryCode)
+	}
+
+	before := country.Value
+	after := compute(before)
+	if after < 0 {
+		return models.AuditEntry{}, fmt.Errorf("%w: %s would be %d", ErrNegativeCount, countryCode, after)
+	}
+	country.Value = after
+
+	return s.appendAudit(models.AuditEntry{
+		Actor:       actor,
+		Action:      action,
+		CountryCode: countryCode,
+		Before:      &before,
+		After:       &after,
+	}), nil
+}
+
+// ResetCounts sets every count to zero and audits the previous counts
+func (s *MemoryStore) ResetCounts(ctx context.Context, actor string) (models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[string]int)
+	total := 0
+	for code, country := range s.countries {
+		if country.Value != 0 {
+			previous[code] = country.Value
+			total += country.Value
+			country.Value = 0
+		}
+	}
+
+	entry, err := resetAuditEntry(actor, previous, total)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+	return s.appendAudit(entry), nil
+}
+
+// SetFrozen freezes or unfreezes a country and audits the change
+func (s *MemoryStore) SetFrozen(ctx context.Context, countryCode string, frozen bool, actor string) (models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.countries[countryCode]; !exists {
+		return models.AuditEntry{}, fmt.Errorf("%w: %s", ErrCountryNotFound, countryCode)
+	}
+
+	action := models.AuditUnfreeze
+	if frozen {
+		action = models.AuditFreeze
+		s.frozen[countryCode] = true
+	} else {
+		delete(s.frozen, countryCode)
+	}
+
+	return s.appendAudit(models.AuditEntry{
+		Actor:       actor,
+		Action:      action,
+		CountryCode: countryCode,
+	}), nil
+}
+
+// FrozenCountries returns the codes of frozen countries
+func (s *MemoryStore) FrozenCountries(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make([]string, 0, len(s.frozen))
+	for code := range s.frozen {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// RecordAudit appends an admin action to the audit log
+func (s *MemoryStore) RecordAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.appendAudit(entry), nil
+}
+
+// AuditLog returns the most recent admin actions, newest first
+func (s *MemoryStore) AuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []models.AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, s.audit[i])
+	}
+	return entries, nil
+}
+
+// appendAudit stores an audit entry with the next ID. Must be called with mu held.
+func (s *MemoryStore) appendAudit(entry models.AuditEntry) models.AuditEntry {
+	entry.ID = int64(len(s.audit) + 1)
+	entry.CreatedAt = time.Now().UTC()
+	s.audit = append(s.audit, entry)
+	return entry
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"clickflag-go-backend/constants"
+	"clickflag-go-backend/models"
+)
+
+// MemoryStore keeps country counts in memory. It is meant for tests and local experiments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	countries map[string]*models.Country
+	history   map[HistoryInterval]map[historyKey]int64
+	rolledUp  map[HistoryInterval]int64 // Rollup watermark of each rolled up table
+	frozen    map[string]bool
+	audit     []models.AuditEntry
+}
+
+// historyKey identifies one country's bucket in a history table
+type historyKey struct {
+	countryCode string
+	bucket      int64
+}
+
+// NewMemoryStore creates an in-memory store with every country seeded at zero
+func NewMemoryStore() *MemoryStore {
+	countries := make(map[string]*models.Country, len(constants.AllCountryCodes))
+	for i, code := range constants.AllCountryCodes {
+		countries[code] = &models.Country{
+			ID:          i + 1,
+			CountryCode: code,
+		}
+	}
+
+	history := make(map[HistoryInterval]map[historyKey]int64, len(historyIntervals))
+	for _, interval := range historyIntervals {
+		history[interval] = make(map[historyKey]int64)
+	}
+
+	return &MemoryStore{
+		countries: countries,
+		history:   history,
+		rolledUp:  make(map[HistoryInterval]int64),
+		frozen:    make(map[string]bool),
+	}
+}
+
+// LoadCountries returns a copy of every stored country
+func (s *MemoryStore) LoadCountries(ctx context.Context) ([]models.Country, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	countries := make([]models.Country, 0, len(s.countries))
+	for _, country := range s.countries {
+		countries = append(countries, *country)
+	}
+
+	return countries, nil
+}
+
+// ApplyIncrements adds the batch to the stored counts and the minute history;
+// a batch with unknown countries is rejected whole
+func (s *MemoryStore) ApplyIncrements(ctx context.Context, increments map[string]int32, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failed := make(map[string]error)
+	for countryCode := range increments {
+		if _, exists := s.countries[countryCode]; !exists {
+			failed[countryCode] = fmt.Errorf("%w: %s", ErrCountryNotFound, countryCode)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &IncrementError{Failed: failed}
+	}
+
+	bucket := IntervalMinute.Truncate(at).Unix()
+	for countryCode, amount := range increments {
+		s.countries[countryCode].Value += int(amount)
+		s.history[IntervalMinute][historyKey{countryCode, bucket}] += int64(amount)
+	}
+
+	return nil
+}
+
+// History returns click counts per bucket from the table matching the query interval
+func (s *MemoryStore) History(ctx context.Context, query HistoryQuery) ([]models.HistoryPoint, error) {
+	if !query.Interval.IsValid() {
+		return nil, fmt.Errorf("invalid history interval %q", query.Interval)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(query.CountryCodes))
+	for _, code := range query.CountryCodes {
+		wanted[code] = true
+	}
+
+	from := query.Interval.Truncate(query.From).Unix()
+	to := query.To.Unix()
+
+	points := []models.HistoryPoint{}
+	for key, clicks := range s.history[query.Interval] {
+		if !wanted[key.countryCode] || key.bucket < from || key.bucket >= to {
+			continue
+		}
+		points = append(points, models.HistoryPoint{
+			CountryCode: key.countryCode,
+			Bucket:      time.Unix(key.bucket, 0).UTC(),
+			Clicks:      clicks,
+		})
+	}
+
+	sort.Slice(points, func(a, b int) bool {
+		if points[a].CountryCode != points[b].CountryCode {
+			return points[a].CountryCode < points[b].CountryCode
+		}
+		return points[a].Bucket.Before(points[b].Bucket)
+	})
+
+	return points, nil
+}
+
+// RollupHistory recomputes hourly and daily buckets since the previous hour and day, or
+// since the rollup watermark when runs were missed
+func (s *MemoryStore) RollupHistory(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rollup := range historyRollups {
+		s.rollup(rollup.source, rollup.target, now)
+		s.rolledUp[rollup.target] = rollup.target.Truncate(now).Unix()
+	}
+	return nil
+}
+
+// rollup sums source buckets into target buckets starting at the previous target bucket
+// or the watermark
+func (s *MemoryStore) rollup(source, target HistoryInterval, now time.Time) {
+	size := int64(target.Duration().Seconds())
+	since := rollupSince(target, s.rolledUp[target], now)
+
+	sums := make(map[historyKey]int64)
+	for key, clicks := range s.history[source] {
+		if key.bucket < since {
+			continue
+		}
+		sums[historyKey{key.countryCode, key.bucket - key.bucket%size}] += clicks
+	}
+
+	for key, clicks := range sums {
+		s.history[target][key] = clicks
+	}
+}
+
+// PruneHistory deletes buckets older than the retention of their table, keeping the ones
+// not rolled up yet
+func (s *MemoryStore) PruneHistory(ctx context.Context, policy RetentionPolicy, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, interval := range historyIntervals {
+		retention := policy.forInterval(interval)
+		if retention <= 0 {
+			continue
+		}
+
+		cutoff := pruneCutoff(interval, retention, s.rolledUp, now)
+		for key := range s.history[interval] {
+			if key.bucket < cutoff {
+				delete(s.history[interval], key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Ping always succeeds for the memory store
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
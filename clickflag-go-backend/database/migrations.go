@@ -0,0 +1,383 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"clickflag-go-backend/tracing"
+)
+
+// migrationFilePattern matches NNN_name.sql and NNN_name.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+?)(\.down)?\.sql$`)
+
+// Dialect identifies the SQL flavour a migrator talks to
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite3"
+	DialectPostgres Dialect = "postgres"
+)
+
+// rebind rewrites ? placeholders into the dialect's placeholder syntax
+func (d Dialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations and records them in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// LoadMigrations discovers NNN_*.sql migrations (and optional NNN_*.down.sql rollbacks)
+// in the root of fsys, sorted by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations directory: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration file %s: %w", entry.Name(), err)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version}
+			byVersion[version] = migration
+		}
+
+		if match[3] == ".down" {
+			migration.DownSQL = string(content)
+			continue
+		}
+
+		if migration.UpSQL != "" {
+			return nil, fmt.Errorf("duplicate migration version %03d (%s and %s)", version, migration.Name, match[2])
+		}
+
+		sum := sha256.Sum256(content)
+		migration.Name = match[2]
+		migration.UpSQL = string(content)
+		migration.Checksum = hex.EncodeToString(sum[:])
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %03d has a down file but no up file", version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(a, b int) bool { return migrations[a].Version < migrations[b].Version })
+	return migrations, nil
+}
+
+// NewMigrator creates a SQLite migrator for the migrations found in fsys
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	return NewMigratorForDialect(db, DialectSQLite, fsys)
+}
+
+// NewMigratorForDialect creates a migrator for the migrations found in fsys
+func NewMigratorForDialect(db *sql.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction.
+// It refuses to run when an applied migration's checksum no longer matches its file.
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, done := applied[migration.Version]; done {
+			continue
+		}
+
+		if err := m.apply(migration); err != nil {
+			return count, err
+		}
+
+		slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, done := applied[migration.Version]; !done {
+			continue
+		}
+
+		if migration.DownSQL == "" {
+			return count, fmt.Errorf("migration %03d_%s has no down file", migration.Version, migration.Name)
+		}
+
+		if err := m.revert(migration); err != nil {
+			return count, err
+		}
+
+		slog.Info("Rolled back migration", "version", migration.Version, "name", migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if record, done := applied[migration.Version]; done {
+			status.Applied = true
+			status.AppliedAt = record.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// ensureTable creates the schema_migrations table if needed
+func (m *Migrator) ensureTable() error {
+	timestampType := "DATETIME"
+	if m.dialect == DialectPostgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+
+	statement := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at ` + timestampType + ` NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	ctx, span := m.dialect.startStatement(context.Background(), "CREATE", "schema_migrations", statement)
+	_, err := m.db.ExecContext(ctx, statement)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations reads the schema_migrations table
+func (m *Migrator) appliedMigrations() (applied map[int]appliedMigration, err error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	statement := `SELECT version, checksum, applied_at FROM schema_migrations`
+	ctx, span := m.dialect.startStatement(context.Background(), "SELECT", "schema_migrations", statement)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := m.db.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, fmt.Errorf("error querying schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied = make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		applied[version] = record
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema_migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// verify checks that every applied migration still exists with an unchanged checksum
+func (m *Migrator) verify() (map[int]appliedMigration, error) {
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, record := range applied {
+		migration, exists := known[version]
+		if !exists {
+			return nil, fmt.Errorf("migration %03d is applied but its file is missing", version)
+		}
+		if migration.Checksum != record.checksum {
+			return nil, fmt.Errorf("checksum mismatch for applied migration %03d_%s: file was modified after it was applied", version, migration.Name)
+		}
+	}
+
+	return applied, nil
+}
+
+// apply runs a migration and records it in one transaction
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction for migration %03d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	ctx, span := m.dialect.startStatement(context.Background(), "MIGRATE", migrationName(migration), migration.UpSQL)
+	_, err = tx.ExecContext(ctx, migration.UpSQL)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("error executing migration %03d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	statement := m.dialect.rebind(`INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)`)
+	ctx, span = m.dialect.startStatement(context.Background(), "INSERT", "schema_migrations", statement)
+	_, err = tx.ExecContext(ctx, statement, migration.Version, migration.Name, migration.Checksum)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("error recording migration %03d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing migration %03d: %w", migration.Version, err)
+	}
+
+	return nil
+}
+
+// migrationName returns the file name of a migration without its extension, e.g. 001_create_countries_table
+func migrationName(migration Migration) string {
+	return fmt.Sprintf("%03d_%s", migration.Version, migration.Name)
+}
+
+// revert runs a migration's down file and removes its record in one transaction
+func (m *Migrator) revert(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction for migration %03d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	ctx, span := m.dialect.startStatement(context.Background(), "REVERT", migrationName(migration), migration.DownSQL)
+	_, err = tx.ExecContext(ctx, migration.DownSQL)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("error rolling back migration %03d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	statement := m.dialect.rebind(`DELETE FROM schema_migrations WHERE version = ?`)
+	ctx, span = m.dialect.startStatement(context.Background(), "DELETE", "schema_migrations", statement)
+	_, err = tx.ExecContext(ctx, statement, migration.Version)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("error removing migration record %03d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing rollback of migration %03d: %w", migration.Version, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"clickflag-go-backend/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresStore stores country counts in PostgreSQL
+type PostgresStore struct {
+	sqlStore
+}
+
+// PoolOptions sizes a PostgreSQL connection pool
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultPoolOptions returns the pool settings used when none are configured
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{MaxOpenConns: 10, MaxIdleConns: 5, ConnMaxLifetime: time.Hour}
+}
+
+// ConnectPostgres opens a PostgreSQL connection pool without running migrations
+func ConnectPostgres(databaseURL string, pool PoolOptions) (*sql.DB, error) {
+	conn, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	conn.SetMaxOpenConns(pool.MaxOpenConns)
+	conn.SetMaxIdleConns(pool.MaxIdleConns)
+	conn.SetConnMaxLifetime(pool.ConnMaxLifetime)
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error pinging database: %w", err)
+	}
+
+	return conn, nil
+}
+
+// NewPostgresMigrator returns a migrator for the embedded PostgreSQL migrations,
+// or for the files in migrationsDir when an override directory is set
+func NewPostgresMigrator(conn *sql.DB, migrationsDir string) (*Migrator, error) {
+	if migrationsDir != "" {
+		slog.Info("Using migrations from override directory", "dir", migrationsDir)
+		return NewMigratorForDialect(conn, DialectPostgres, os.DirFS(migrationsDir))
+	}
+
+	return NewMigratorForDialect(conn, DialectPostgres, migrations.PostgresFS)
+}
+
+// OpenPostgresStore connects to PostgreSQL and applies pending migrations.
+// Migrations embedded in the binary are used unless migrationsDir is set.
+func OpenPostgresStore(databaseURL string, migrationsDir string, pool PoolOptions) (*PostgresStore, error) {
+	conn, err := ConnectPostgres(databaseURL, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewPostgresMigrator(conn, migrationsDir)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error running migrations: %w", err)
+	}
+
+	slog.Info("PostgreSQL store initialized successfully", "migrations_applied", applied)
+
+	return NewPostgresStore(conn), nil
+}
+
+// NewPostgresStore creates a store on an open PostgreSQL connection without running migrations
+func NewPostgresStore(conn *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		sqlStore: sqlStore{db: conn, dialect: DialectPostgres},
+	}
+}
+
+// DB returns the underlying database connection
+func (s *PostgresStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database connection
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,340 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/tracing"
+)
+
+// forUpdate returns the row locking clause of the dialect; SQLite serializes writers instead
+func (d Dialect) forUpdate() string {
+	if d == DialectPostgres {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// SetCountryValue replaces a country's count and audits the change in one transaction
+func (s *sqlStore) SetCountryValue(ctx context.Context, countryCode string, value int, actor string) (models.AuditEntry, error) {
+	return s.updateCountryValue(ctx, countryCode, actor, models.AuditSetValue, func(before int) int {
+		return value
+	})
+}
+
+// AdjustCountryValue adds delta to a country's count and audits the change in one transaction
+func (s *sqlStore) AdjustCountryValue(ctx context.Context, countryCode string, delta int, actor string) (models.AuditEntry, error) {
+	return s.updateCountryValue(ctx, countryCode, actor, models.AuditAdjustValue, func(before int) int {
+		return before + delta
+	})
+}
+
+// updateCountryValue locks a country's row, writes the value computed from its current
+// count and audits the change, all in one transaction
+func (s *sqlStore) updateCountryValue(ctx context.Context, countryCode, actor, action string, compute func(before int) int) (models.AuditEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before int
+	statement := s.dialect.rebind(`SELECT value FROM countries WHERE country_code = ?` + s.dialect.forUpdate())
+	spanCtx, span := s.startStatement(ctx, "SELECT", "countries", statement)
+	err = tx.QueryRowContext(spanCtx, statement, countryCode).Scan(&before)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AuditEntry{}, fmt.Errorf("%w: %s", ErrCountryNotFound, countryCode)
+	}
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error reading country value for %s: %w", countryCode, err)
+	}
+
+	after := compute(before)
+	if after < 0 {
+		return models.AuditEntry{}, fmt.Errorf("%w: %s would be %d", ErrNegativeCount, countryCode, after)
+	}
+
+	statement = s.dialect.rebind(`UPDATE countries SET value = ? WHERE country_code = ?`)
+	spanCtx, span = s.startStatement(ctx, "UPDATE", "countries", statement)
+	_, err = tx.ExecContext(spanCtx, statement, after, countryCode)
+	tracing.End(span, err)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error updating country value for %s: %w", countryCode, err)
+	}
+
+	entry, err := s.insertAudit(ctx, tx, models.AuditEntry{
+		Actor:       actor,
+		Action:      action,
+		CountryCode: countryCode,
+		Before:      &before,
+		After:       &after,
+	})
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error committing country value: %w", err)
+	}
+
+	return entry, nil
+}
+
+// ResetCounts sets every count to zero. The audit entry holds the previous total, and the
+// previous non-zero counts as JSON in its detail.
+func (s *sqlStore) ResetCounts(ctx context.Context, actor string) (models.AuditEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, total, err := s.lockCounts(ctx, tx)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	statement := `UPDATE countries SET value = 0 WHERE value <> 0`
+	spanCtx, span := s.startStatement(ctx, "UPDATE", "countries", statement)
+	_, err = tx.ExecContext(spanCtx, statement)
+	tracing.End(span, err)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error resetting counts: %w", err)
+	}
+
+	entry, err := resetAuditEntry(actor, previous, total)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+	if entry, err = s.insertAudit(ctx, tx, entry); err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error committing reset: %w", err)
+	}
+
+	return entry, nil
+}
+
+// lockCounts reads and locks the non-zero counts and returns them with their total
+func (s *sqlStore) lockCounts(ctx context.Context, tx *sql.Tx) (previous map[string]int, total int, err error) {
+	statement := `SELECT country_code, value FROM countries WHERE value <> 0` + s.dialect.forUpdate()
+	ctx, span := s.startStatement(ctx, "SELECT", "countries", statement)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := tx.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading counts: %w", err)
+	}
+	defer rows.Close()
+
+	previous = make(map[string]int)
+	for rows.Next() {
+		var code string
+		var value int
+		if err := rows.Scan(&code, &value); err != nil {
+			return nil, 0, fmt.Errorf("error scanning count: %w", err)
+		}
+		previous[code] = value
+		total += value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating counts: %w", err)
+	}
+
+	return previous, total, nil
+}
+
+// SetFrozen adds or removes a country from frozen_countries and audits the change
+func (s *sqlStore) SetFrozen(ctx context.Context, countryCode string, frozen bool, actor string) (models.AuditEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	statement := s.dialect.rebind(`SELECT 1 FROM countries WHERE country_code = ?`)
+	spanCtx, span := s.startStatement(ctx, "SELECT", "countries", statement)
+	err = tx.QueryRowContext(spanCtx, statement, countryCode).Scan(&exists)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AuditEntry{}, fmt.Errorf("%w: %s", ErrCountryNotFound, countryCode)
+	}
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error reading country %s: %w", countryCode, err)
+	}
+
+	action := models.AuditUnfreeze
+	if frozen {
+		action = models.AuditFreeze
+		statement = s.dialect.rebind(`
+			INSERT INTO frozen_countries (country_code, frozen_by, frozen_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (country_code) DO NOTHING
+		`)
+		spanCtx, span = s.startStatement(ctx, "INSERT", "frozen_countries", statement)
+		_, err = tx.ExecContext(spanCtx, statement, countryCode, actor, time.Now().UnixMilli())
+	} else {
+		statement = s.dialect.rebind(`DELETE FROM frozen_countries WHERE country_code = ?`)
+		spanCtx, span = s.startStatement(ctx, "DELETE", "frozen_countries", statement)
+		_, err = tx.ExecContext(spanCtx, statement, countryCode)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error updating frozen state of %s: %w", countryCode, err)
+	}
+
+	entry, err := s.insertAudit(ctx, tx, models.AuditEntry{
+		Actor:       actor,
+		Action:      action,
+		CountryCode: countryCode,
+	})
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error committing frozen state: %w", err)
+	}
+
+	return entry, nil
+}
+
+// FrozenCountries returns the codes of frozen countries
+func (s *sqlStore) FrozenCountries(ctx context.Context) (codes []string, err error) {
+	statement := `SELECT country_code FROM frozen_countries ORDER BY country_code`
+	ctx, span := s.startStatement(ctx, "SELECT", "frozen_countries", statement)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, fmt.Errorf("error querying frozen countries: %w", err)
+	}
+	defer rows.Close()
+
+	codes = []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("error scanning frozen country: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating frozen countries: %w", err)
+	}
+
+	return codes, nil
+}
+
+// RecordAudit appends an admin action to the audit log
+func (s *sqlStore) RecordAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	return s.insertAudit(ctx, s.db, entry)
+}
+
+// AuditLog returns the most recent admin actions, newest first
+func (s *sqlStore) AuditLog(ctx context.Context, limit int) (entries []models.AuditEntry, err error) {
+	statement := s.dialect.rebind(`
+		SELECT id, actor, action, country_code, before_value, after_value, detail, created_at
+		FROM admin_audit
+		ORDER BY id DESC
+		LIMIT ?
+	`)
+	ctx, span := s.startStatement(ctx, "SELECT", "admin_audit", statement)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, statement, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries = []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var countryCode sql.NullString
+		var before, after sql.NullInt64
+		var createdAt int64
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &countryCode, &before, &after, &entry.Detail, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %w", err)
+		}
+
+		entry.CountryCode = countryCode.String
+		entry.Before = nullableInt(before)
+		entry.After = nullableInt(after)
+		entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// execQueryer is implemented by *sql.DB and *sql.Tx
+type execQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertAudit writes an audit entry and returns it with its ID and timestamp
+func (s *sqlStore) insertAudit(ctx context.Context, db execQueryer, entry models.AuditEntry) (models.AuditEntry, error) {
+	entry.CreatedAt = time.Now().UTC()
+
+	var countryCode any
+	if entry.CountryCode != "" {
+		countryCode = entry.CountryCode
+	}
+
+	statement := s.dialect.rebind(`
+		INSERT INTO admin_audit (actor, action, country_code, before_value, after_value, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`)
+	spanCtx, span := s.startStatement(ctx, "INSERT", "admin_audit", statement)
+	err := db.QueryRowContext(spanCtx, statement, entry.Actor, entry.Action, countryCode, entry.Before, entry.After, entry.Detail, entry.CreatedAt.UnixMilli()).Scan(&entry.ID)
+	tracing.End(span, err)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error recording audit entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// resetAuditEntry builds the audit entry of a reset from the previous non-zero counts
+func resetAuditEntry(actor string, previous map[string]int, total int) (models.AuditEntry, error) {
+	detail, err := json.Marshal(previous)
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error encoding previous counts: %w", err)
+	}
+
+	after := 0
+	return models.AuditEntry{
+		Actor:  actor,
+		Action: models.AuditResetCounts,
+		Before: &total,
+		After:  &after,
+		Detail: string(detail),
+	}, nil
+}
+
+// nullableInt converts a nullable column into an optional int
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
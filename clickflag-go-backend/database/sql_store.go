@@ -0,0 +1,306 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// sqlStore implements Store on top of database/sql; the dialect only changes placeholders
+type sqlStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// Ping checks the database connection
+func (s *sqlStore) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("error pinging %s: %w", s.dialect.system(), err)
+	}
+	return nil
+}
+
+// LoadCountries retrieves all countries from the database
+func (s *sqlStore) LoadCountries(ctx context.Context) (countries []models.Country, err error) {
+	query := `
+		SELECT id, country_code, value
+		FROM countries
+	`
+
+	ctx, span := s.startStatement(ctx, "SELECT", "countries", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying countries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var country models.Country
+		err := rows.Scan(
+			&country.ID,
+			&country.CountryCode,
+			&country.Value,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning country: %w", err)
+		}
+		countries = append(countries, country)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating countries: %w", err)
+	}
+
+	return countries, nil
+}
+
+// ApplyIncrements increments the value of each country in the batch by its pending count
+// and adds the counts to the minute history bucket containing at.
+// The whole batch is written with prepared statements inside a single transaction.
+func (s *sqlStore) ApplyIncrements(ctx context.Context, increments map[string]int32, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	incrementQuery := s.dialect.rebind(`
+		UPDATE countries
+		SET value = value + ?
+		WHERE country_code = ?
+	`)
+	stmt, err := tx.PrepareContext(ctx, incrementQuery)
+	if err != nil {
+		return fmt.Errorf("error preparing increment statement: %w", err)
+	}
+	defer stmt.Close()
+
+	historyQuery := s.dialect.rebind(`
+		INSERT INTO country_clicks_minute (country_code, bucket, clicks)
+		VALUES (?, ?, ?)
+		ON CONFLICT (country_code, bucket)
+		DO UPDATE SET clicks = country_clicks_minute.clicks + excluded.clicks
+	`)
+	historyStmt, err := tx.PrepareContext(ctx, historyQuery)
+	if err != nil {
+		return fmt.Errorf("error preparing history statement: %w", err)
+	}
+	defer historyStmt.Close()
+
+	bucket := IntervalMinute.Truncate(at).Unix()
+	failed := make(map[string]error)
+	for countryCode, amount := range increments {
+		rowsAffected, err := s.execIncrement(ctx, stmt, incrementQuery, countryCode, amount)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			failed[countryCode] = fmt.Errorf("%w: %s", ErrCountryNotFound, countryCode)
+			continue
+		}
+
+		spanCtx, span := s.startStatement(ctx, "INSERT", "country_clicks_minute", historyQuery)
+		_, err = historyStmt.ExecContext(spanCtx, countryCode, bucket, amount)
+		tracing.End(span, err)
+		if err != nil {
+			return fmt.Errorf("error recording history for %s: %w", countryCode, err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &IncrementError{Failed: failed}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing increments: %w", err)
+	}
+
+	return nil
+}
+
+// execIncrement runs the prepared increment statement for one country and returns the rows affected
+func (s *sqlStore) execIncrement(ctx context.Context, stmt *sql.Stmt, query, countryCode string, amount int32) (rowsAffected int64, err error) {
+	ctx, span := s.startStatement(ctx, "UPDATE", "countries", query)
+	span.SetAttributes(attribute.String("country_code", countryCode))
+	defer func() { tracing.End(span, err) }()
+
+	result, err := stmt.ExecContext(ctx, amount, countryCode)
+	if err != nil {
+		return 0, fmt.Errorf("error updating country value for %s: %w", countryCode, err)
+	}
+
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// History returns click counts per bucket from the table matching the query interval
+func (s *sqlStore) History(ctx context.Context, query HistoryQuery) (points []models.HistoryPoint, err error) {
+	if !query.Interval.IsValid() {
+		return nil, fmt.Errorf("invalid history interval %q", query.Interval)
+	}
+
+	if len(query.CountryCodes) == 0 {
+		return []models.HistoryPoint{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(query.CountryCodes)), ",")
+	statement := s.dialect.rebind(`
+		SELECT country_code, bucket, clicks
+		FROM ` + query.Interval.table() + `
+		WHERE country_code IN (` + placeholders + `)
+		AND bucket >= ? AND bucket < ?
+		ORDER BY country_code, bucket
+	`)
+
+	args := make([]any, 0, len(query.CountryCodes)+2)
+	for _, code := range query.CountryCodes {
+		args = append(args, code)
+	}
+	args = append(args, query.Interval.Truncate(query.From).Unix(), query.To.Unix())
+
+	ctx, span := s.startStatement(ctx, "SELECT", query.Interval.table(), statement)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying history: %w", err)
+	}
+	defer rows.Close()
+
+	points = []models.HistoryPoint{}
+	for rows.Next() {
+		var point models.HistoryPoint
+		var bucket int64
+		if err := rows.Scan(&point.CountryCode, &bucket, &point.Clicks); err != nil {
+			return nil, fmt.Errorf("error scanning history: %w", err)
+		}
+		point.Bucket = time.Unix(bucket, 0).UTC()
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating history: %w", err)
+	}
+
+	return points, nil
+}
+
+// RollupHistory recomputes hourly buckets from minute buckets and daily buckets from hourly
+// buckets, starting at the previous hour and day so late flushes are picked up, or at the
+// rollup watermark when runs were missed
+func (s *sqlStore) RollupHistory(ctx context.Context, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, rollup := range historyRollups {
+		watermark, err := s.rollupWatermark(ctx, tx, rollup.target)
+		if err != nil {
+			return err
+		}
+
+		size := int64(rollup.target.Duration().Seconds())
+		since := rollupSince(rollup.target, watermark, now)
+
+		// The bucket size is inlined so the SELECT and GROUP BY expressions are identical
+		statement := s.dialect.rebind(fmt.Sprintf(`
+			INSERT INTO %[1]s (country_code, bucket, clicks)
+			SELECT country_code, bucket - (bucket %% %[3]d), SUM(clicks)
+			FROM %[2]s
+			WHERE bucket >= ?
+			GROUP BY country_code, bucket - (bucket %% %[3]d)
+			ON CONFLICT (country_code, bucket)
+			DO UPDATE SET clicks = excluded.clicks
+		`, rollup.target.table(), rollup.source.table(), size))
+
+		spanCtx, span := s.startStatement(ctx, "INSERT", rollup.target.table(), statement)
+		_, err = tx.ExecContext(spanCtx, statement, since)
+		tracing.End(span, err)
+		if err != nil {
+			return fmt.Errorf("error rolling up %s history: %w", rollup.target, err)
+		}
+
+		// Every bucket before the current one is complete
+		statement = s.dialect.rebind(`
+			INSERT INTO history_rollups (target_interval, rolled_up_to)
+			VALUES (?, ?)
+			ON CONFLICT (target_interval)
+			DO UPDATE SET rolled_up_to = excluded.rolled_up_to
+		`)
+		spanCtx, span = s.startStatement(ctx, "INSERT", "history_rollups", statement)
+		_, err = tx.ExecContext(spanCtx, statement, string(rollup.target), rollup.target.Truncate(now).Unix())
+		tracing.End(span, err)
+		if err != nil {
+			return fmt.Errorf("error storing %s rollup watermark: %w", rollup.target, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing history rollup: %w", err)
+	}
+
+	return nil
+}
+
+// rollupWatermark returns the first bucket of target not rolled up yet, or 0 before the first rollup
+func (s *sqlStore) rollupWatermark(ctx context.Context, db execQueryer, target HistoryInterval) (int64, error) {
+	var watermark int64
+	statement := s.dialect.rebind(`SELECT rolled_up_to FROM history_rollups WHERE target_interval = ?`)
+	spanCtx, span := s.startStatement(ctx, "SELECT", "history_rollups", statement)
+	err := db.QueryRowContext(spanCtx, statement, string(target)).Scan(&watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Never rolled up
+		watermark, err = 0, nil
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return 0, fmt.Errorf("error reading %s rollup watermark: %w", target, err)
+	}
+	return watermark, nil
+}
+
+// PruneHistory deletes buckets older than the retention of their table, keeping the ones
+// not rolled up yet
+func (s *sqlStore) PruneHistory(ctx context.Context, policy RetentionPolicy, now time.Time) error {
+	watermarks := make(map[HistoryInterval]int64, len(historyRollups))
+	for _, rollup := range historyRollups {
+		watermark, err := s.rollupWatermark(ctx, s.db, rollup.target)
+		if err != nil {
+			return err
+		}
+		watermarks[rollup.target] = watermark
+	}
+
+	for _, interval := range historyIntervals {
+		retention := policy.forInterval(interval)
+		if retention <= 0 {
+			continue
+		}
+
+		statement := s.dialect.rebind(`DELETE FROM ` + interval.table() + ` WHERE bucket < ?`)
+		spanCtx, span := s.startStatement(ctx, "DELETE", interval.table(), statement)
+		_, err := s.db.ExecContext(spanCtx, statement, pruneCutoff(interval, retention, watermarks, now))
+		tracing.End(span, err)
+		if err != nil {
+			return fmt.Errorf("error pruning %s history: %w", interval, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package database
+
+import "database/sql"
+
+// SQLiteStore stores country counts in SQLite
+type SQLiteStore struct {
+	sqlStore
+}
+
+// NewSQLiteStore creates a store on an open SQLite connection (see InitDatabase)
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{
+		sqlStore: sqlStore{db: db, dialect: DialectSQLite},
+	}
+}
+
+// Close closes the underlying database connection
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"clickflag-go-backend/models"
+)
+
+// ErrCountryNotFound is reported for increments of a country that does not exist in the store
+var ErrCountryNotFound = errors.New("country code not found")
+
+// ErrNegativeCount is reported when an admin change would make a count negative
+var ErrNegativeCount = errors.New("count cannot be negative")
+
+// historyIntervals lists history tables from finest to coarsest
+var historyIntervals = []HistoryInterval{IntervalMinute, IntervalHour, IntervalDay}
+
+// HistoryInterval is the bucket size of a history query
+type HistoryInterval string
+
+const (
+	IntervalMinute HistoryInterval = "minute"
+	IntervalHour   HistoryInterval = "hour"
+	IntervalDay    HistoryInterval = "day"
+)
+
+// Duration returns the length of one bucket
+func (i HistoryInterval) Duration() time.Duration {
+	switch i {
+	case IntervalMinute:
+		return time.Minute
+	case IntervalHour:
+		return time.Hour
+	case IntervalDay:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// IsValid reports whether the interval is one of minute, hour or day
+func (i HistoryInterval) IsValid() bool {
+	return i.Duration() > 0
+}
+
+// Truncate returns the start (UTC) of the bucket containing t
+func (i HistoryInterval) Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(i.Duration())
+}
+
+// table returns the history table holding buckets of this interval
+func (i HistoryInterval) table() string {
+	return "country_clicks_" + string(i)
+}
+
+// historyRollups pairs each rolled up history table with the finer table it is computed from
+var historyRollups = []struct {
+	source HistoryInterval
+	target HistoryInterval
+}{
+	{IntervalMinute, IntervalHour},
+	{IntervalHour, IntervalDay},
+}
+
+// rollupSince returns the first target bucket a rollup recomputes: the previous one, so
+// late flushes are picked up, or the watermark when buckets were missed, e.g. while the
+// service was down. A zero watermark means target was never rolled up.
+func rollupSince(target HistoryInterval, watermark int64, now time.Time) int64 {
+	since := target.Truncate(now).Add(-target.Duration()).Unix()
+	if watermark > 0 && watermark < since {
+		return watermark
+	}
+	return since
+}
+
+// pruneCutoff returns the bucket before which interval's buckets are deleted. Buckets the
+// coarser table has not been rolled up from yet, at or after its watermark, are kept.
+func pruneCutoff(interval HistoryInterval, retention time.Duration, watermarks map[HistoryInterval]int64, now time.Time) int64 {
+	cutoff := now.Add(-retention).Unix()
+	for _, rollup := range historyRollups {
+		if rollup.source != interval {
+			continue
+		}
+		if watermark := watermarks[rollup.target]; watermark > 0 && watermark < cutoff {
+			cutoff = watermark
+		}
+	}
+	return cutoff
+}
+
+// RetentionPolicy is how long each history table keeps its buckets; zero keeps them forever
+type RetentionPolicy struct {
+	Minute time.Duration
+	Hour   time.Duration
+	Day    time.Duration
+}
+
+// forInterval returns the retention of the given interval's table
+func (p RetentionPolicy) forInterval(interval HistoryInterval) time.Duration {
+	switch interval {
+	case IntervalMinute:
+		return p.Minute
+	case IntervalHour:
+		return p.Hour
+	case IntervalDay:
+		return p.Day
+	default:
+		return 0
+	}
+}
+
+// HistoryQuery selects click history for one or more countries
+type HistoryQuery struct {
+	CountryCodes []string
+	From         time.Time
+	To           time.Time
+	Interval     HistoryInterval
+}
+
+// Store persists country counts. Implementations must be safe for concurrent use.
+type Store interface {
+	// LoadCountries returns every country with its current count
+	LoadCountries(ctx context.Context) ([]models.Country, error)
+
+	// ApplyIncrements atomically adds a batch of pending clicks to the stored counts
+	// and to the minute history bucket containing at. Either everything is stored or nothing is.
+	ApplyIncrements(ctx context.Context, increments map[string]int32, at time.Time) error
+
+	// History returns click counts per time bucket for the queried countries
+	History(ctx context.Context, query HistoryQuery) ([]models.HistoryPoint, error)
+
+	// RollupHistory recomputes the hourly and daily buckets touched since the previous hour
+	// and day, and every complete bucket since the last rollup
+	RollupHistory(ctx context.Context, now time.Time) error
+
+	// PruneHistory deletes buckets older than the retention policy allows that were rolled up
+	PruneHistory(ctx context.Context, policy RetentionPolicy, now time.Time) error
+
+	// SetCountryValue replaces a country's count and records the change in the audit log
+	SetCountryValue(ctx context.Context, countryCode string, value int, actor string) (models.AuditEntry, error)
+
+	// AdjustCountryValue adds delta to a country's count and records the change in the audit log
+	AdjustCountryValue(ctx context.Context, countryCode string, delta int, actor string) (models.AuditEntry, error)
+
+	// ResetCounts sets every count to zero and records the previous counts in the audit log
+	ResetCounts(ctx context.Context, actor string) (models.AuditEntry, error)
+
+	// SetFrozen freezes or unfreezes a country and records the change in the audit log
+	SetFrozen(ctx context.Context, countryCode string, frozen bool, actor string) (models.AuditEntry, error)
+
+	// FrozenCountries returns the codes of frozen countries
+	FrozenCountries(ctx context.Context) ([]string, error)
+
+	// RecordAudit appends an admin action that does not change stored data to the audit log
+	RecordAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error)
+
+	// AuditLog returns the most recent admin actions, newest first
+	AuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error)
+
+	// Ping checks that the store is reachable
+	Ping(ctx context.Context) error
+
+	// Close releases the store's resources
+	Close() error
+}
+
+// IncrementError reports the countries that caused a batch to be rolled back.
+// No increment of the batch was stored.
+type IncrementError struct {
+	Failed map[string]error
+}
+
+// Error implements the error interface
+func (e *IncrementError) Error() string {
+	codes := make([]string, 0, len(e.Failed))
+	for code := range e.Failed {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return fmt.Sprintf("batch rolled back, increments failed for %d countries: %s", len(codes), strings.Join(codes, ", "))
+}
+
+// Compile-time checks that every implementation satisfies Store
+var (
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
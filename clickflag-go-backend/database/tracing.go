@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"clickflag-go-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// system returns the OpenTelemetry db.system.name of the dialect
+func (d Dialect) system() string {
+	if d == DialectPostgres {
+		return "postgresql"
+	}
+	return "sqlite"
+}
+
+// startStatement starts a client span for one SQL statement of the store
+func (s *sqlStore) startStatement(ctx context.Context, operation, table, statement string) (context.Context, trace.Span) {
+	return s.dialect.startStatement(ctx, operation, table, statement)
+}
+
+// startStatement starts a client span for one SQL statement. Only the statement text is
+// recorded, never its arguments.
+func (d Dialect) startStatement(ctx context.Context, operation, table, statement string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", d.system()),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.collection.name", table),
+			attribute.String("db.query.text", strings.Join(strings.Fields(statement), " ")),
+		),
+	)
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/config"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// defaultAuditLimit is the number of audit entries returned when limit is omitted
+	defaultAuditLimit = 100
+	// maxAuditLimit caps the audit entries returned in one request
+	maxAuditLimit = 1000
+)
+
+// AdminHandler handles authenticated admin requests. Every action is written to the audit log.
+type AdminHandler struct {
+	store     database.Store
+	cache     *cache.Cache
+	processor *processor.BackgroundProcessor
+	reloader  *config.Reloader
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(store database.Store, cache *cache.Cache, bgProcessor *processor.BackgroundProcessor) *AdminHandler {
+	return &AdminHandler{
+		store:     store,
+		cache:     cache,
+		processor: bgProcessor,
+	}
+}
+
+// SetReloader enables POST /admin/v1/config/reload
+func (h *AdminHandler) SetReloader(reloader *config.Reloader) {
+	h.reloader = reloader
+}
+
+// ListCountries returns every country's count as currently served, without pending clicks
+// GET /admin/v1/countries
+func (h *AdminHandler) ListCountries(c *fiber.Ctx) error {
+	countries := h.cache.GetCountries()
+
+	counts := make([]models.CountryAPI, 0, len(countries))
+	for _, country := range countries {
+		counts = append(counts, models.CountryAPI{CountryCode: country.CountryCode, Value: country.Value})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].CountryCode < counts[j].CountryCode
+	})
+
+	return adminSuccess(c, "Countries retrieved", counts)
+}
+
+// SetCountryValue replaces a country's count
+// PUT /admin/v1/countries/:code {"value": 42}
+func (h *AdminHandler) SetCountryValue(c *fiber.Ctx) error {
+	code, ok := adminCountryCode(c)
+	if !ok {
+		return adminError(c, fiber.StatusBadRequest, "Invalid country code.")
+	}
+
+	var request models.AdminValueRequest
+	if err := c.BodyParser(&request); err != nil || request.Value == nil {
+		return adminError(c, fiber.StatusBadRequest, "Request body must contain a value.")
+	}
+	if *request.Value < 0 {
+		return adminError(c, fiber.StatusUnprocessableEntity, "Value must not be negative.")
+	}
+
+	return h.changeValue(c, func(actor string) (models.AuditEntry, error) {
+		return h.store.SetCountryValue(requestContext(c), code, *request.Value, actor)
+	})
+}
+
+// AdjustCountryValue adds a positive or negative delta to a country's count
+// POST /admin/v1/countries/:code/adjust {"delta": -10}
+func (h *AdminHandler) AdjustCountryValue(c *fiber.Ctx) error {
+	code, ok := adminCountryCode(c)
+	if !ok {
+		return adminError(c, fiber.StatusBadRequest, "Invalid country code.")
+	}
+
+	var request models.AdminValueRequest
+	if err := c.BodyParser(&request); err != nil || request.Delta == nil {
+		return adminError(c, fiber.StatusBadRequest, "Request body must contain a delta.")
+	}
+
+	return h.changeValue(c, func(actor string) (models.AuditEntry, error) {
+		return h.store.AdjustCountryValue(requestContext(c), code, *request.Delta, actor)
+	})
+}
+
+// ResetCounts sets every count to zero; it must be confirmed with ?confirm=true
+// POST /admin/v1/countries/reset?confirm=true
+func (h *AdminHandler) ResetCounts(c *fiber.Ctx) error {
+	if c.Query("confirm") != "true" {
+		return adminError(c, fiber.StatusBadRequest, "Resetting all counts must be confirmed with confirm=true.")
+	}
+
+	return h.changeValue(c, func(actor string) (models.AuditEntry, error) {
+		return h.store.ResetCounts(requestContext(c), actor)
+	})
+}
+
+// changeValue flushes pending clicks so the change applies on top of them, runs it and
+// refreshes the cache so the new counts are served and streamed right away
+func (h *AdminHandler) changeValue(c *fiber.Ctx, change func(actor string) (models.AuditEntry, error)) error {
+	ctx := requestContext(c)
+	logger := logging.FromContext(ctx)
+
+	if _, err := h.processor.Flush(ctx); err != nil {
+		logger.Error("Error flushing before admin change", "error", err)
+		return adminError(c, fiber.StatusServiceUnavailable, "Pending clicks could not be flushed, try again later.")
+	}
+
+	entry, err := change(middleware.GetAdminActor(c))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	if err := h.processor.RefreshCache(ctx); err != nil {
+		logger.Error("Error refreshing cache after admin change", "error", err)
+	}
+
+	logger.Info("Admin action", "actor", entry.Actor, "action", entry.Action, "country_code", entry.CountryCode)
+	return adminSuccess(c, "Count updated", entry)
+}
+
+// FreezeCountry rejects clicks for a country until it is unfrozen
+// POST /admin/v1/countries/:code/freeze
+func (h *AdminHandler) FreezeCountry(c *fiber.Ctx) error {
+	return h.setFrozen(c, true)
+}
+
+// UnfreezeCountry accepts clicks for a frozen country again
+// DELETE /admin/v1/countries/:code/freeze
+func (h *AdminHandler) UnfreezeCountry(c *fiber.Ctx) error {
+	return h.setFrozen(c, false)
+}
+
+// setFrozen stores a country's frozen state and applies it to the cache checked by click handlers
+func (h *AdminHandler) setFrozen(c *fiber.Ctx, frozen bool) error {
+	code, ok := adminCountryCode(c)
+	if !ok {
+		return adminError(c, fiber.StatusBadRequest, "Invalid country code.")
+	}
+
+	ctx := requestContext(c)
+	entry, err := h.store.SetFrozen(ctx, code, frozen, middleware.GetAdminActor(c))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	codes, err := h.store.FrozenCountries(ctx)
+	if err != nil {
+		return h.storeError(c, err)
+	}
+	h.cache.SetFrozenCountries(codes)
+
+	logging.FromContext(ctx).Info("Admin action", "actor", entry.Actor, "action", entry.Action, "country_code", code)
+	return adminSuccess(c, "Frozen countries updated", fiber.Map{
+		"entry":  entry,
+		"frozen": codes,
+	})
+}
+
+// ListFrozen returns the codes of frozen countries
+// GET /admin/v1/countries/frozen
+func (h *AdminHandler) ListFrozen(c *fiber.Ctx) error {
+	return adminSuccess(c, "Frozen countries retrieved", h.cache.FrozenCountries())
+}
+
+// Flush writes pending clicks to the store now instead of on the next scheduled flush
+// POST /admin/v1/flush
+func (h *AdminHandler) Flush(c *fiber.Ctx) error {
+	ctx := requestContext(c)
+
+	result, flushErr := h.processor.Flush(ctx)
+	detail := fmt.Sprintf("applied=%d retried=%d dropped=%d", len(result.Applied), len(result.Retried), len(result.Dropped))
+	if flushErr != nil {
+		detail += ": " + flushErr.Error()
+	}
+
+	entry, err := h.store.RecordAudit(ctx, models.AuditEntry{
+		Actor:  middleware.GetAdminActor(c),
+		Action: models.AuditFlush,
+		Detail: detail,
+	})
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	if flushErr != nil {
+		logging.FromContext(ctx).Error("Admin flush failed", "actor", entry.Actor, "error", flushErr)
+		return adminError(c, fiber.StatusServiceUnavailable, "Flush failed, pending clicks will be retried.")
+	}
+
+	logging.FromContext(ctx).Info("Admin action", "actor", entry.Actor, "action", entry.Action, "countries", len(result.Applied))
+	return adminSuccess(c, "Pending clicks flushed", fiber.Map{
+		"entry":   entry,
+		"applied": result.Applied,
+	})
+}
+
+// Refresh reloads the cache from the store
+// POST /admin/v1/refresh
+func (h *AdminHandler) Refresh(c *fiber.Ctx) error {
+	ctx := requestContext(c)
+
+	refreshErr := h.processor.RefreshCache(ctx)
+	detail := ""
+	if refreshErr != nil {
+		detail = refreshErr.Error()
+	}
+
+	entry, err := h.store.RecordAudit(ctx, models.AuditEntry{
+		Actor:  middleware.GetAdminActor(c),
+		Action: models.AuditRefresh,
+		Detail: detail,
+	})
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	if refreshErr != nil {
+		logging.FromContext(ctx).Error("Admin refresh failed", "actor", entry.Actor, "error", refreshErr)
+		return adminError(c, fiber.StatusServiceUnavailable, "Cache refresh failed.")
+	}
+
+	logging.FromContext(ctx).Info("Admin action", "actor", entry.Actor, "action", entry.Action)
+	return adminSuccess(c, "Cache refreshed", entry)
+}
+
+// ReloadConfig re-reads the configuration and applies the settings that can change at runtime
+// POST /admin/v1/config/reload
+func (h *AdminHandler) ReloadConfig(c *fiber.Ctx) error {
+	ctx := requestContext(c)
+	if h.reloader == nil {
+		return adminError(c, fiber.StatusServiceUnavailable, "Configuration reload is not available.")
+	}
+
+	result, reloadErr := h.reloader.Reload()
+	detail := ""
+	if result != nil {
+		detail = result.String()
+	}
+	if reloadErr != nil {
+		detail = strings.TrimPrefix(detail+"; "+reloadErr.Error(), "; ")
+	}
+
+	entry, err := h.store.RecordAudit(ctx, models.AuditEntry{
+		Actor:  middleware.GetAdminActor(c),
+		Action: models.AuditReload,
+		Detail: detail,
+	})
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	var configErr *config.Error
+	switch {
+	case errors.As(reloadErr, &configErr):
+		logging.FromContext(ctx).Warn("Rejected invalid configuration", "actor", entry.Actor, "error", reloadErr)
+		return adminError(c, fiber.StatusUnprocessableEntity, reloadErr.Error())
+	case reloadErr != nil:
+		logging.FromContext(ctx).Error("Admin configuration reload failed", "actor", entry.Actor, "error", reloadErr)
+		return adminError(c, fiber.StatusInternalServerError, "Configuration reload failed, the running configuration was kept.")
+	}
+
+	logging.FromContext(ctx).Info("Admin action", "actor", entry.Actor, "action", entry.Action, "detail", detail)
+	return adminSuccess(c, "Configuration reloaded", fiber.Map{
+		"entry":            entry,
+		"applied":          result.Applied,
+		"restart_required": result.RestartRequired,
+	})
+}
+
+// AuditLog returns the most recent admin actions, newest first
+// GET /admin/v1/audit?limit=100
+func (h *AdminHandler) AuditLog(c *fiber.Ctx) error {
+	limit := defaultAuditLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxAuditLimit {
+			return adminError(c, fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d.", maxAuditLimit))
+		}
+		limit = parsed
+	}
+
+	entries, err := h.store.AuditLog(requestContext(c), limit)
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	return adminSuccess(c, "Audit log retrieved", entries)
+}
+
+// storeError maps a store error to a response
+func (h *AdminHandler) storeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, database.ErrCountryNotFound):
+		return adminError(c, fiber.StatusNotFound, "Country not found.")
+	case errors.Is(err, database.ErrNegativeCount):
+		return adminError(c, fiber.StatusUnprocessableEntity, "Count must not become negative.")
+	default:
+		logging.FromContext(requestContext(c)).Error("Admin store error", "error", err)
+		return adminError(c, fiber.StatusInternalServerError, "Internal server error.")
+	}
+}
+
+// adminCountryCode returns the upper-cased :code parameter if it is a known country
+func adminCountryCode(c *fiber.Ctx) (string, bool) {
+	code := strings.ToUpper(c.Params("code"))
+	return code, models.IsValidCountryCode(code)
+}
+
+// adminSuccess writes a successful admin response
+func adminSuccess(c *fiber.Ctx, message string, data any) error {
+	return c.JSON(models.CountryResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// adminError writes a failed admin response
+func adminError(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(models.CountryResponse{
+		Success: false,
+		Message: message,
+	})
+}
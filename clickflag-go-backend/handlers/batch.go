@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/constants"
+	"clickflag-go-backend/journal"
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/metrics"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BatchLimits caps how many clicks a client can plausibly submit
+type BatchLimits struct {
+	ClientRate  float64       // Clicks per second per client, all countries together
+	CountryRate float64       // Clicks per second per client for a single country
+	Window      time.Duration // Longest client-side window a batch may cover
+	MaxKeys     int           // Clients, and client and country pairs, tracked at most; 0 is unbounded
+}
+
+// BatchHandler accepts clicks aggregated on the client
+type BatchHandler struct {
+	cache          *cache.Cache
+	journal        *journal.Journal
+	clientBuckets  *ratelimit.BucketSet
+	countryBuckets *ratelimit.BucketSet
+}
+
+// NewBatchHandler creates a new batch handler. Each client may send a window's
+// worth of clicks at once and then keeps earning them at the configured rates.
+// The journal may be nil when click journaling is disabled.
+func NewBatchHandler(cache *cache.Cache, clickJournal *journal.Journal, limits BatchLimits) *BatchHandler {
+	return &BatchHandler{
+		cache:          cache,
+		journal:        clickJournal,
+		clientBuckets:  ratelimit.NewBoundedBucketSet(limits.ClientRate, windowBurst(limits.ClientRate, limits.Window), limits.MaxKeys),
+		countryBuckets: ratelimit.NewBoundedBucketSet(limits.CountryRate, windowBurst(limits.CountryRate, limits.Window), limits.MaxKeys),
+	}
+}
+
+// windowBurst returns how many clicks fit in one window at rate
+func windowBurst(rate float64, window time.Duration) int {
+	burst := int(rate * window.Seconds())
+	if burst < 1 {
+		return 1
+	}
+	return burst
+}
+
+// AddCountriesBatch adds aggregated clicks, e.g. {"TR": 37, "US": 2}, to pending updates.
+// Every country is accepted or rejected on its own and reported in the response.
+// POST /api/v1/countries/batch
+func (h *BatchHandler) AddCountriesBatch(c *fiber.Ctx) error {
+	var batch map[string]int
+	if err := json.Unmarshal(c.Body(), &batch); err != nil {
+		logging.FromContext(c.UserContext()).Warn("Error parsing batch body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.CountryResponse{
+			Success: false,
+			Message: "Invalid request body, expected an object of country codes to click counts",
+		})
+	}
+
+	if len(batch) == 0 || len(batch) > constants.GetCountryCount() {
+		return c.Status(fiber.StatusBadRequest).JSON(models.CountryResponse{
+			Success: false,
+			Message: fmt.Sprintf("Batch must contain between 1 and %d countries", constants.GetCountryCount()),
+		})
+	}
+
+	clientKey := middleware.ClientIP(c)
+	now := time.Now()
+
+	results := make(map[string]models.BatchItemResult, len(batch))
+	accepted, rateLimited := 0, 0
+
+	for code, clicks := range batch {
+		result := h.addClicks(requestContext(c), clientKey, code, clicks, now)
+		switch {
+		case result.Status == "accepted":
+			accepted++
+		case result.rateLimited:
+			rateLimited++
+		}
+		results[code] = result.BatchItemResult
+	}
+
+	status := fiber.StatusOK
+	message := "Clicks added to pending updates successfully"
+	switch {
+	case accepted == len(batch):
+	case accepted > 0:
+		status = fiber.StatusMultiStatus
+		message = "Some clicks were rejected"
+	case rateLimited == len(batch):
+		status = fiber.StatusTooManyRequests
+		message = "Rate limit exceeded. Please try again later."
+	default:
+		status = fiber.StatusUnprocessableEntity
+		message = "No clicks were accepted"
+	}
+
+	return c.Status(status).JSON(models.CountryResponse{
+		Success: accepted == len(batch),
+		Message: message,
+		Data:    results,
+	})
+}
+
+// batchItem is the outcome of one country of a batch
+type batchItem struct {
+	models.BatchItemResult
+	rateLimited bool
+}
+
+// addClicks validates and rate limits the clicks for one country, then records them
+func (h *BatchHandler) addClicks(ctx context.Context, clientKey, code string, clicks int, now time.Time) batchItem {
+	item := batchItem{BatchItemResult: models.BatchItemResult{Clicks: clicks, Status: "rejected"}}
+
+	if !models.IsValidCountryCode(code) {
+		item.Error = "Invalid country code."
+		return item
+	}
+	if clicks < 1 {
+		item.Error = "Click count must be positive"
+		return item
+	}
+	if h.cache.IsFrozen(code) {
+		item.Error = frozenMessage
+		return item
+	}
+
+	countryBucket := h.countryBuckets.Get(clientKey + "|" + code)
+	if !countryBucket.AllowNAt(now, clicks) {
+		metrics.RateLimitRejections.WithLabelValues("batch_country").Inc()
+		item.Error = "Too many clicks for this country, please slow down"
+		item.rateLimited = true
+		return item
+	}
+
+	clientBucket := h.clientBuckets.Get(clientKey)
+	if !clientBucket.AllowNAt(now, clicks) {
+		countryBucket.Refund(clicks)
+		metrics.RateLimitRejections.WithLabelValues("batch_client").Inc()
+		item.Error = "Too many clicks, please slow down"
+		item.rateLimited = true
+		return item
+	}
+
+	if err := recordClicks(ctx, h.cache, h.journal, code, int32(clicks), "batch"); err != nil {
+		logging.FromContext(ctx).Error("Error journaling clicks", "country_code", code, "clicks", clicks, "error", err)
+		countryBucket.Refund(clicks)
+		clientBucket.Refund(clicks)
+		item.Error = "Clicks could not be recorded, please try again"
+		return item
+	}
+
+	item.Status = "accepted"
+	return item
+}
@@ -1,50 +1,139 @@
 package handlers
 
 import (
-	"log"
-	"time"
+	"context"
+	"strconv"
+	"strings"
 
 	"clickflag-go-backend/cache"
+	"clickflag-go-backend/journal"
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/metrics"
+	"clickflag-go-backend/middleware"
 	"clickflag-go-backend/models"
+	"clickflag-go-backend/ratelimit"
+	"clickflag-go-backend/tracing"
 
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CountryHandler handles country-related HTTP requests
 type CountryHandler struct {
-	cache *cache.Cache
+	cache   *cache.Cache
+	journal *journal.Journal
+	limiter *ratelimit.Limiter
 }
 
-// NewCountryHandler creates a new country handler
-func NewCountryHandler(cache *cache.Cache) *CountryHandler {
+// NewCountryHandler creates a new country handler.
+// The journal may be nil when click journaling is disabled.
+func NewCountryHandler(cache *cache.Cache, clickJournal *journal.Journal) *CountryHandler {
 	return &CountryHandler{
-		cache: cache,
+		cache:   cache,
+		journal: clickJournal,
 	}
 }
 
-// GetCountries returns all countries from cache
+// SetClickLimiter limits clicks per client IP and per client IP and country
+func (h *CountryHandler) SetClickLimiter(limiter *ratelimit.Limiter) {
+	h.limiter = limiter
+}
+
+// GetCountries returns all countries from cache, served from the response body
+// encoded at the last refresh (compressed when the client accepts it).
+// The snapshot version is sent as ETag, so If-None-Match is answered with 304 while
+// nothing changed, and ?since=<version> returns only the countries changed after it.
 func (h *CountryHandler) GetCountries(c *fiber.Ctx) error {
-	countries := h.cache.GetCountries()
+	if since := c.Query("since"); since != "" {
+		return h.getCountriesSince(c, since)
+	}
 
-	// Convert map to object format for JSON response
-	countryMap := make(map[string]int, len(countries))
-	for _, country := range countries {
-		countryMap[country.CountryCode] = country.Value
+	encoded := h.cache.GetEncodedResponse()
+	if notModified(c, encoded.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Vary(fiber.HeaderAcceptEncoding)
+
+	// Without Accept-Encoding, AcceptsEncodings would pick the first offer
+	acceptsAny := c.Get(fiber.HeaderAcceptEncoding) != ""
+
+	switch {
+	case !acceptsAny:
+		return c.Send(encoded.JSON)
+	case encoded.Brotli != nil && c.AcceptsEncodings("br") == "br":
+		c.Set(fiber.HeaderContentEncoding, "br")
+		return c.Send(encoded.Brotli)
+	case encoded.Gzip != nil && c.AcceptsEncodings("gzip") == "gzip":
+		c.Set(fiber.HeaderContentEncoding, "gzip")
+		return c.Send(encoded.Gzip)
+	default:
+		return c.Send(encoded.JSON)
+	}
+}
+
+// getCountriesSince returns the countries changed after the given version, or all
+// of them when that version is too old to compute a delta
+func (h *CountryHandler) getCountriesSince(c *fiber.Ctx, since string) error {
+	version, err := strconv.ParseUint(since, 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.CountryResponse{
+			Success: false,
+			Message: "Invalid since version",
+		})
+	}
+
+	changed, current, ok := h.cache.ChangedSince(version)
+	if notModified(c, current) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	if !ok {
+		countries, snapshotVersion := h.cache.GetSnapshot()
+		changed = make(map[string]int, len(countries))
+		for _, country := range countries {
+			changed[country.CountryCode] = country.Value
+		}
+		current = snapshotVersion
+		setVersionETag(c, current)
 	}
 
 	return c.JSON(models.CountryResponse{
 		Success: true,
-		Message: "Countries retrieved successfully",
-		Data:    countryMap,
+		Message: "Changed countries retrieved successfully",
+		Version: current,
+		Data:    changed,
 	})
 }
 
+// notModified sets the version ETag and reports whether the client already has that version
+func notModified(c *fiber.Ctx, version uint64) bool {
+	etag := setVersionETag(c, version)
+
+	for _, candidate := range strings.Split(c.Get(fiber.HeaderIfNoneMatch), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// setVersionETag sets the ETag header for a snapshot version and returns it
+func setVersionETag(c *fiber.Ctx, version uint64) string {
+	etag := `"` + strconv.FormatUint(version, 10) + `"`
+	c.Set(fiber.HeaderETag, etag)
+	return etag
+}
+
 // AddCountry adds a country code to pending updates
 func (h *CountryHandler) AddCountry(c *fiber.Ctx) error {
 	var request models.CountryRequest
 
 	if err := c.BodyParser(&request); err != nil {
-		log.Printf("Error parsing request body: %v", err)
+		logging.FromContext(c.UserContext()).Warn("Error parsing request body", "error", err)
 		return c.Status(fiber.StatusBadRequest).JSON(models.CountryResponse{
 			Success: false,
 			Message: "Invalid request body",
@@ -66,10 +155,31 @@ func (h *CountryHandler) AddCountry(c *fiber.Ctx) error {
 		})
 	}
 
-	// Add to pending updates
-	h.cache.AddPendingUpdate(request.CountryCode)
+	// Frozen clicks are refused before they spend any rate limit tokens
+	if h.cache.IsFrozen(request.CountryCode) {
+		return c.Status(fiber.StatusLocked).JSON(models.CountryResponse{
+			Success: false,
+			Message: frozenMessage,
+		})
+	}
+
+	if h.limiter != nil {
+		decision := h.limiter.Allow(middleware.ClientIP(c), request.CountryCode)
+		middleware.SetRateLimitHeaders(c, decision)
+		if !decision.Allowed {
+			return middleware.TooManyRequests(c, "click")
+		}
+	}
+
+	if err := recordClicks(requestContext(c), h.cache, h.journal, request.CountryCode, 1, "http"); err != nil {
+		logging.FromContext(c.UserContext()).Error("Error journaling click", "country_code", request.CountryCode, "error", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.CountryResponse{
+			Success: false,
+			Message: "Click could not be recorded, please try again",
+		})
+	}
 
-	log.Printf("Added country code %s to pending updates", request.CountryCode)
+	logging.FromContext(c.UserContext()).Debug("Added country code to pending updates", "country_code", request.CountryCode)
 
 	return c.JSON(models.CountryResponse{
 		Success: true,
@@ -81,13 +191,26 @@ func (h *CountryHandler) AddCountry(c *fiber.Ctx) error {
 	})
 }
 
-// HealthCheck returns health status
-func (h *CountryHandler) HealthCheck(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC(),
-		"cache": fiber.Map{
-			"has_pending": h.cache.HasPendingUpdates(),
-		},
+// frozenMessage is the error for clicks on a country frozen by an admin
+const frozenMessage = "Clicks for this country are currently frozen"
+
+// recordClicks records validated clicks in the journal, then adds them to pending updates
+// in one atomic operation. source labels the submission path in the click metrics.
+func recordClicks(ctx context.Context, cache *cache.Cache, clickJournal *journal.Journal, countryCode string, amount int32, source string) (err error) {
+	_, span := tracing.Tracer().Start(ctx, "AddPendingUpdate", trace.WithAttributes(
+		attribute.String("country_code", countryCode),
+		attribute.Int("clicks", int(amount)),
+		attribute.String("source", source),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	err = clickJournal.Append(countryCode, amount, func() {
+		cache.AddPendingUpdateBy(countryCode, amount)
 	})
+	if err != nil {
+		return err
+	}
+
+	metrics.ClicksTotal.WithLabelValues(countryCode, source).Add(float64(amount))
+	return nil
 }
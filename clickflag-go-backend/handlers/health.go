@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/health"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler serves liveness, readiness and health endpoints
+type HealthHandler struct {
+	cache   *cache.Cache
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a new health handler running the checker's readiness checks
+func NewHealthHandler(cache *cache.Cache, checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		cache:   cache,
+		checker: checker,
+	}
+}
+
+// Livez reports that the process is running and serving requests; it checks no dependencies
+// GET /livez
+func (h *HealthHandler) Livez(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz runs every readiness check and answers 503 if any of them fails, so the
+// instance is taken out of rotation
+// GET /readyz
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	report := h.checker.Run(requestContext(c))
+	if !report.Ready() {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(report)
+}
+
+// HealthCheck returns health status; ?verbose=1 adds per-check status, latency and last error
+// GET /health
+func (h *HealthHandler) HealthCheck(c *fiber.Ctx) error {
+	report := h.checker.Run(requestContext(c))
+
+	status := "healthy"
+	if !report.Ready() {
+		status = "unhealthy"
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+
+	response := fiber.Map{
+		"status":    status,
+		"timestamp": time.Now().UTC(),
+		"cache": fiber.Map{
+			"has_pending": h.cache.HasPendingUpdates(),
+		},
+	}
+	if verbose := c.Query("verbose"); verbose == "1" || verbose == "true" {
+		response["checks"] = report.Checks
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"clickflag-go-backend/constants"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// maxHistoryPoints caps the buckets returned per country
+	maxHistoryPoints = 1440
+	// maxHistoryCountries caps the countries compared in one request
+	maxHistoryCountries = 10
+)
+
+// defaultHistoryWindow is the range returned when from is omitted
+var defaultHistoryWindow = map[database.HistoryInterval]time.Duration{
+	database.IntervalMinute: time.Hour,
+	database.IntervalHour:   24 * time.Hour,
+	database.IntervalDay:    30 * 24 * time.Hour,
+}
+
+// HistoryHandler handles click history HTTP requests
+type HistoryHandler struct {
+	store database.Store
+}
+
+// NewHistoryHandler creates a new history handler
+func NewHistoryHandler(store database.Store) *HistoryHandler {
+	return &HistoryHandler{
+		store: store,
+	}
+}
+
+// historyRequest is a validated history query
+type historyRequest struct {
+	from     time.Time
+	to       time.Time
+	interval database.HistoryInterval
+}
+
+// GetCountryHistory returns the click time series of one country
+// GET /api/v1/countries/:code/history?from=&to=&interval=minute|hour|day
+func (h *HistoryHandler) GetCountryHistory(c *fiber.Ctx) error {
+	code := c.Params("code")
+	if !constants.IsValidCountryCode(code) {
+		return historyError(c, fiber.StatusBadRequest, "Invalid country code.")
+	}
+
+	series, status, err := h.loadHistory(c, []string{code})
+	if err != nil {
+		return historyError(c, status, err.Error())
+	}
+
+	return c.JSON(models.CountryResponse{
+		Success: true,
+		Message: "History retrieved successfully",
+		Data:    series[0],
+	})
+}
+
+// GetHistory returns the click time series of several countries for comparison
+// GET /api/v1/countries/history?codes=TR,US&from=&to=&interval=minute|hour|day
+func (h *HistoryHandler) GetHistory(c *fiber.Ctx) error {
+	codes, err := parseCountryCodes(c.Query("codes"))
+	if err != nil {
+		return historyError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	series, status, err := h.loadHistory(c, codes)
+	if err != nil {
+		return historyError(c, status, err.Error())
+	}
+
+	return c.JSON(models.CountryResponse{
+		Success: true,
+		Message: "History retrieved successfully",
+		Data:    series,
+	})
+}
+
+// loadHistory validates the query string and reads one series per country from the store
+func (h *HistoryHandler) loadHistory(c *fiber.Ctx, codes []string) ([]models.CountryHistory, int, error) {
+	request, err := parseHistoryRequest(c, time.Now().UTC())
+	if err != nil {
+		return nil, fiber.StatusBadRequest, err
+	}
+
+	points, err := h.store.History(requestContext(c), database.HistoryQuery{
+		CountryCodes: codes,
+		From:         request.from,
+		To:           request.to,
+		Interval:     request.interval,
+	})
+	if err != nil {
+		logging.FromContext(c.UserContext()).Error("Error reading click history", "country_codes", codes, "error", err)
+		return nil, fiber.StatusInternalServerError, errors.New("history could not be loaded")
+	}
+
+	return buildSeries(codes, points, request), fiber.StatusOK, nil
+}
+
+// parseHistoryRequest validates from, to and interval. Without an interval the
+// finest one that fits the range in maxHistoryPoints buckets is used, which is
+// also the cheapest table that can answer the query.
+func parseHistoryRequest(c *fiber.Ctx, now time.Time) (historyRequest, error) {
+	request := historyRequest{to: now}
+
+	if value := c.Query("to"); value != "" {
+		to, err := parseHistoryTime(value)
+		if err != nil {
+			return request, fmt.Errorf("invalid to: %w", err)
+		}
+		request.to = to
+	}
+
+	interval := database.HistoryInterval(c.Query("interval"))
+	if interval != "" && !interval.IsValid() {
+		return request, errors.New("invalid interval, expected minute, hour or day")
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := parseHistoryTime(value)
+		if err != nil {
+			return request, fmt.Errorf("invalid from: %w", err)
+		}
+		request.from = from
+	} else {
+		window := defaultHistoryWindow[interval]
+		if interval == "" {
+			window = defaultHistoryWindow[database.IntervalHour]
+		}
+		request.from = request.to.Add(-window)
+	}
+
+	if !request.from.Before(request.to) {
+		return request, errors.New("parameter from must be before to")
+	}
+
+	if interval == "" {
+		interval = intervalForRange(request.to.Sub(request.from))
+	}
+	request.interval = interval
+
+	// Align the range to whole buckets so every point covers a full interval
+	request.from = interval.Truncate(request.from)
+
+	if buckets := request.to.Sub(request.from) / interval.Duration(); buckets > maxHistoryPoints {
+		return request, fmt.Errorf("range is too large for interval %s (max %d buckets)", interval, maxHistoryPoints)
+	}
+
+	return request, nil
+}
+
+// intervalForRange returns the finest interval that covers the range in maxHistoryPoints buckets
+func intervalForRange(span time.Duration) database.HistoryInterval {
+	for _, interval := range []database.HistoryInterval{database.IntervalMinute, database.IntervalHour} {
+		if span <= maxHistoryPoints*interval.Duration() {
+			return interval
+		}
+	}
+	return database.IntervalDay
+}
+
+// parseHistoryTime accepts RFC 3339 timestamps or unix seconds
+func parseHistoryTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC 3339 or unix seconds")
+	}
+	return t.UTC(), nil
+}
+
+// parseCountryCodes splits and validates a comma separated list of country codes
+func parseCountryCodes(value string) ([]string, error) {
+	if value == "" {
+		return nil, errors.New("parameter codes is required")
+	}
+
+	seen := make(map[string]bool)
+	codes := []string{}
+	for _, code := range strings.Split(value, ",") {
+		code = strings.TrimSpace(code)
+		if !constants.IsValidCountryCode(code) {
+			return nil, fmt.Errorf("invalid country code: %s", code)
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+
+	if len(codes) > maxHistoryCountries {
+		return nil, fmt.Errorf("too many country codes (max %d)", maxHistoryCountries)
+	}
+
+	return codes, nil
+}
+
+// buildSeries groups points per country and fills buckets without clicks with zero
+func buildSeries(codes []string, points []models.HistoryPoint, request historyRequest) []models.CountryHistory {
+	clicks := make(map[string]map[int64]int64, len(codes))
+	for _, code := range codes {
+		clicks[code] = make(map[int64]int64)
+	}
+	for _, point := range points {
+		clicks[point.CountryCode][point.Bucket.Unix()] = point.Clicks
+	}
+
+	step := request.interval.Duration()
+	series := make([]models.CountryHistory, 0, len(codes))
+	for _, code := range codes {
+		history := models.CountryHistory{
+			CountryCode: code,
+			Interval:    string(request.interval),
+			From:        request.from,
+			To:          request.to,
+			Points:      []models.HistoryBucket{},
+		}
+
+		for bucket := request.from; bucket.Before(request.to); bucket = bucket.Add(step) {
+			count := clicks[code][bucket.Unix()]
+			history.Total += count
+			history.Points = append(history.Points, models.HistoryBucket{Bucket: bucket, Clicks: count})
+		}
+
+		series = append(series, history)
+	}
+
+	return series
+}
+
+// historyError writes a failed history response. Errors are lowercase, so the first
+// letter is capitalized like the messages of the other responses.
+func historyError(c *fiber.Ctx, status int, message string) error {
+	if message != "" {
+		message = strings.ToUpper(message[:1]) + message[1:]
+	}
+	return c.Status(status).JSON(models.CountryResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// requestContext returns the request deadline set by the timeout middleware
+func requestContext(c *fiber.Ctx) context.Context {
+	if ctx, ok := c.Locals("ctx").(context.Context); ok {
+		return ctx
+	}
+	return c.UserContext()
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/stream"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamWriteTimeout bounds each write so dead clients are detected and dropped
+const streamWriteTimeout = 10 * time.Second
+
+// streamRetry is the reconnect delay suggested to EventSource clients, in milliseconds
+const streamRetry = 3000
+
+// StreamHandler serves live country counts as Server-Sent Events
+type StreamHandler struct {
+	cache       *cache.Cache
+	broadcaster *stream.Broadcaster
+	heartbeat   time.Duration
+}
+
+// NewStreamHandler creates a new stream handler sending a heartbeat comment every heartbeat interval
+func NewStreamHandler(cache *cache.Cache, broadcaster *stream.Broadcaster, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		cache:       cache,
+		broadcaster: broadcaster,
+		heartbeat:   heartbeat,
+	}
+}
+
+// Stream sends a snapshot of all counts (or the events missed since Last-Event-ID)
+// followed by the countries that change after each cache refresh
+// GET /api/v1/stream
+func (h *StreamHandler) Stream(c *fiber.Ctx) error {
+	lastEventID := parseLastEventID(c)
+
+	sub, err := h.broadcaster.Subscribe(lastEventID)
+	if err != nil {
+		if errors.Is(err, stream.ErrTooManySubscribers) {
+			c.Set(fiber.HeaderRetryAfter, "5")
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.CountryResponse{
+			Success: false,
+			Message: "Live stream is unavailable, please try again later",
+		})
+	}
+
+	// Counts are absolute, so a snapshot taken after subscribing may safely
+	// overlap with the first events
+	var snapshot map[string]int
+	if !sub.Resumed {
+		snapshot = make(map[string]int)
+		for code, country := range h.cache.GetCountries() {
+			snapshot[code] = country.Value
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		if err := h.writeInitial(w, conn, sub, snapshot); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(h.heartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					// Dropped as too slow or the server is shutting down; the client resumes on reconnect
+					return
+				}
+				if err := writeStreamEvent(w, conn, "counts", event.ID, event.Counts); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := flushStream(w, conn, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeInitial sends the retry hint and either the full snapshot or the missed events
+func (h *StreamHandler) writeInitial(w *bufio.Writer, conn net.Conn, sub *stream.Subscription, snapshot map[string]int) error {
+	if err := flushStream(w, conn, fmt.Sprintf("retry: %d\n\n", streamRetry)); err != nil {
+		return err
+	}
+
+	if !sub.Resumed {
+		return writeStreamEvent(w, conn, "snapshot", sub.LastID, snapshot)
+	}
+
+	for _, event := range sub.Missed {
+		if err := writeStreamEvent(w, conn, "counts", event.ID, event.Counts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStreamEvent writes one SSE event with a JSON payload
+func writeStreamEvent(w *bufio.Writer, conn net.Conn, name string, id uint64, counts map[string]int) error {
+	data, err := json.Marshal(counts)
+	if err != nil {
+		slog.Error("Error encoding stream event", "event", name, "id", id, "error", err)
+		return err
+	}
+
+	return flushStream(w, conn, fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", id, name, data))
+}
+
+// flushStream writes and flushes a chunk, extending the write deadline for it
+func flushStream(w *bufio.Writer, conn net.Conn, chunk string) error {
+	if conn != nil {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	}
+
+	if _, err := w.WriteString(chunk); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// parseLastEventID reads the Last-Event-ID header, or the lastEventId query
+// parameter for clients that cannot set headers; 0 means start with a snapshot
+func parseLastEventID(c *fiber.Ctx) uint64 {
+	value := c.Get("Last-Event-ID")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/journal"
+	"clickflag-go-backend/metrics"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/ratelimit"
+	"clickflag-go-backend/stream"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Binary server frames are a type byte, the big-endian uint64 event ID and then
+// one 6-byte entry per country: two ASCII letters and a big-endian int32
+// (the count in a snapshot, the change in a delta). Binary client frames are the
+// two ASCII letters of the clicked country.
+const (
+	binarySnapshot byte = 1
+	binaryDelta    byte = 2
+)
+
+// maxClickFrameSize is the largest click frame accepted from a client
+const maxClickFrameSize = 64
+
+// wsClientIPKey is the Locals key of the client IP, resolved before the upgrade
+const wsClientIPKey = "ws_client_ip"
+
+// clickFrame is a click sent by a client, e.g. {"c":"TR"}
+type clickFrame struct {
+	CountryCode string `json:"c"`
+}
+
+// wsMessage is a JSON frame sent to a client
+type wsMessage struct {
+	Type    string         `json:"type"` // snapshot, delta or error
+	ID      uint64         `json:"id,omitempty"`
+	Data    map[string]int `json:"data,omitempty"`
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message,omitempty"`
+}
+
+// WebSocketHandler accepts clicks and sends count deltas over one WebSocket connection
+type WebSocketHandler struct {
+	cache       *cache.Cache
+	journal     *journal.Journal
+	broadcaster *stream.Broadcaster
+	heartbeat   time.Duration
+	clickRate   float64
+	clickBurst  int
+	limiter     *ratelimit.Limiter
+
+	// Live connections, closed and waited for on shutdown
+	mu      sync.Mutex
+	conns   map[*wsConn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// NewWebSocketHandler creates a new WebSocket handler. Each connection may send
+// clickRate clicks per second with bursts of clickBurst; the journal may be nil.
+func NewWebSocketHandler(cache *cache.Cache, clickJournal *journal.Journal, broadcaster *stream.Broadcaster, heartbeat time.Duration, clickRate float64, clickBurst int) *WebSocketHandler {
+	return &WebSocketHandler{
+		cache:       cache,
+		journal:     clickJournal,
+		broadcaster: broadcaster,
+		heartbeat:   heartbeat,
+		clickRate:   clickRate,
+		clickBurst:  clickBurst,
+		conns:       make(map[*wsConn]struct{}),
+	}
+}
+
+// SetClickLimiter limits clicks per client IP and per client IP and country across every
+// connection of the client, and the HTTP clicks sharing the limiter
+func (h *WebSocketHandler) SetClickLimiter(limiter *ratelimit.Limiter) {
+	h.limiter = limiter
+}
+
+// Upgrade rejects requests that are not WebSocket upgrades
+func (h *WebSocketHandler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(models.CountryResponse{
+			Success: false,
+			Message: "WebSocket upgrade required",
+		})
+	}
+
+	// Locals are copied to the connection, the request is not
+	c.Locals(wsClientIPKey, middleware.ClientIP(c))
+	return c.Next()
+}
+
+// Handler returns the connection handler
+// GET /api/v1/ws?format=json|binary
+func (h *WebSocketHandler) Handler() fiber.Handler {
+	return websocket.New(h.serve)
+}
+
+// wsConn serializes writes to one connection
+type wsConn struct {
+	conn    *websocket.Conn
+	ip      string
+	binary  bool
+	logger  *slog.Logger
+	writeMu sync.Mutex
+	closed  atomic.Bool
+}
+
+// serve sends a snapshot, then reads clicks while a second goroutine sends deltas
+func (h *WebSocketHandler) serve(conn *websocket.Conn) {
+	ip, ok := conn.Locals(wsClientIPKey).(string)
+	if !ok {
+		ip = conn.IP()
+	}
+	client := &wsConn{
+		conn:   conn,
+		ip:     ip,
+		binary: conn.Query("format") == "binary",
+		logger: slog.Default().With("request_id", conn.Locals(middleware.RequestIDKey), "ip", ip),
+	}
+
+	if !h.track(client) {
+		client.close(websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	defer h.untrack(client)
+
+	sub, err := h.broadcaster.Subscribe(0)
+	if err != nil {
+		client.close(websocket.CloseTryAgainLater, "too many live connections")
+		return
+	}
+	defer sub.Close()
+
+	snapshot := make(map[string]int)
+	for code, country := range h.cache.GetCountries() {
+		snapshot[code] = country.Value
+	}
+	if err := client.send(binarySnapshot, sub.LastID, snapshot); err != nil {
+		return
+	}
+
+	// The connection is released when serve returns, so wait for the writer to stop first
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.writeLoop(client, sub, done)
+	}()
+
+	h.readLoop(client)
+
+	close(done)
+	wg.Wait()
+}
+
+// track registers a live connection; it fails once Shutdown has begun
+func (h *WebSocketHandler) track(client *wsConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return false
+	}
+	h.conns[client] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+// untrack removes a connection whose read loop has returned, so it records no more clicks
+func (h *WebSocketHandler) untrack(client *wsConn) {
+	h.mu.Lock()
+	delete(h.conns, client)
+	h.mu.Unlock()
+
+	h.wg.Done()
+}
+
+// Shutdown closes every live connection and waits until none can record another click.
+// New connections are refused from then on.
+func (h *WebSocketHandler) Shutdown(ctx context.Context) error {
+	// Closing under the lock keeps serve from returning and releasing the connection meanwhile
+	h.mu.Lock()
+	h.closing = true
+	for client := range h.conns {
+		client.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error waiting for WebSocket connections to close: %w", ctx.Err())
+	}
+}
+
+// writeLoop sends the deltas of every published event and keeps the connection alive with pings
+func (h *WebSocketHandler) writeLoop(client *wsConn, sub *stream.Subscription, done <-chan struct{}) {
+	ping := time.NewTicker(h.heartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Too slow or shutting down; closing makes the read loop return
+				client.close(websocket.CloseGoingAway, "stream ended")
+				return
+			}
+			if err := client.send(binaryDelta, event.ID, event.Deltas); err != nil {
+				client.conn.Close()
+				return
+			}
+		case <-ping.C:
+			client.writeMu.Lock()
+			err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+			client.writeMu.Unlock()
+			if err != nil {
+				client.conn.Close()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// readLoop records click frames until the connection fails or misses two heartbeats
+func (h *WebSocketHandler) readLoop(client *wsConn) {
+	conn := client.conn
+	limiter := ratelimit.NewTokenBucket(h.clickRate, h.clickBurst)
+
+	conn.SetReadLimit(maxClickFrameSize)
+	conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	})
+
+	for {
+		messageType, payload, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				client.logger.Warn("WebSocket read error", "error", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+		if client.closed.Load() {
+			return
+		}
+
+		code, err := parseClickFrame(messageType, payload)
+		if err != nil {
+			client.sendError("", err.Error())
+			continue
+		}
+
+		if !models.IsValidCountryCode(code) {
+			client.sendError(code, "Invalid country code.")
+			continue
+		}
+
+		if h.cache.IsFrozen(code) {
+			client.sendError(code, frozenMessage)
+			continue
+		}
+
+		// Each connection has its own budget, and all connections of a client IP share
+		// the click limiter, so opening more connections does not raise the limit
+		if !limiter.Allow() {
+			metrics.RateLimitRejections.WithLabelValues("websocket").Inc()
+			client.sendError(code, "Rate limit exceeded. Please slow down.")
+			continue
+		}
+		if h.limiter != nil && !h.limiter.Allow(client.ip, code).Allowed {
+			metrics.RateLimitRejections.WithLabelValues("click").Inc()
+			client.sendError(code, "Rate limit exceeded. Please slow down.")
+			continue
+		}
+
+		if err := recordClicks(context.Background(), h.cache, h.journal, code, 1, "websocket"); err != nil {
+			client.logger.Error("Error journaling click", "country_code", code, "error", err)
+			client.sendError(code, "Click could not be recorded, please try again")
+		}
+	}
+}
+
+// parseClickFrame extracts the country code from a JSON or binary click frame
+func parseClickFrame(messageType int, payload []byte) (string, error) {
+	if messageType == websocket.BinaryMessage {
+		if len(payload) != 2 {
+			return "", errors.New("Binary click frames must be 2 bytes")
+		}
+		return string(payload), nil
+	}
+
+	var frame clickFrame
+	if err := json.Unmarshal(payload, &frame); err != nil {
+		return "", errors.New("Invalid click frame")
+	}
+	return frame.CountryCode, nil
+}
+
+// send writes a snapshot or delta frame in the connection's format
+func (c *wsConn) send(kind byte, id uint64, counts map[string]int) error {
+	if c.binary {
+		return c.write(websocket.BinaryMessage, encodeBinaryCounts(kind, id, counts))
+	}
+
+	messageType := "delta"
+	if kind == binarySnapshot {
+		messageType = "snapshot"
+	}
+
+	payload, err := json.Marshal(wsMessage{Type: messageType, ID: id, Data: counts})
+	if err != nil {
+		return err
+	}
+	return c.write(websocket.TextMessage, payload)
+}
+
+// sendError reports a rejected click; errors are always sent as JSON text frames
+func (c *wsConn) sendError(code, message string) {
+	payload, err := json.Marshal(wsMessage{Type: "error", Code: code, Message: message})
+	if err != nil {
+		return
+	}
+	c.write(websocket.TextMessage, payload)
+}
+
+// write sends one frame with a write deadline
+func (c *wsConn) write(messageType int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return c.conn.WriteMessage(messageType, payload)
+}
+
+// close sends a close frame and closes the connection. A hijacked connection is only
+// closed once its handler returns, so the read deadline is expired to end the read loop.
+func (c *wsConn) close(code int, reason string) {
+	c.closed.Store(true)
+
+	c.writeMu.Lock()
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+
+	c.conn.Close()
+	c.conn.SetReadDeadline(time.Now())
+}
+
+// encodeBinaryCounts encodes counts in the compact binary frame format, sorted by country code
+func encodeBinaryCounts(kind byte, id uint64, counts map[string]int) []byte {
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	frame := make([]byte, 9, 9+6*len(codes))
+	frame[0] = kind
+	binary.BigEndian.PutUint64(frame[1:9], id)
+
+	for _, code := range codes {
+		var entry [6]byte
+		copy(entry[:2], code)
+		binary.BigEndian.PutUint32(entry[2:], uint32(int32(counts[code])))
+		frame = append(frame, entry[:]...)
+	}
+
+	return frame
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/processor"
+)
+
+// Pinger is a dependency that can be pinged, such as database.Store
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck fails when the dependency cannot be pinged
+func PingCheck(pinger Pinger) CheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, pinger.Ping(ctx)
+	}
+}
+
+// FlushCheck fails when no flush has succeeded within maxAge, so an instance whose
+// flusher is stuck or keeps failing is taken out of rotation
+func FlushCheck(status func() processor.FlushStatus, maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		flush := status()
+		age := time.Since(flush.LastSuccess)
+
+		details := map[string]any{
+			"last_success":    flush.LastSuccess.UTC(),
+			"age_seconds":     age.Seconds(),
+			"max_age_seconds": maxAge.Seconds(),
+		}
+		if flush.LastError != nil {
+			details["last_flush_error"] = flush.LastError.Error()
+		}
+
+		if age > maxAge {
+			if flush.LastError != nil {
+				return details, fmt.Errorf("no successful flush for %s: %w", age.Round(time.Second), flush.LastError)
+			}
+			return details, fmt.Errorf("no successful flush for %s", age.Round(time.Second))
+		}
+		return details, nil
+	}
+}
+
+// CacheCheck fails when the country cache is empty
+func CacheCheck(cacheInstance *cache.Cache) CheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		countries, version := cacheInstance.GetSnapshot()
+
+		details := map[string]any{
+			"countries": len(countries),
+			"version":   version,
+		}
+		if lastRefresh := cacheInstance.LastRefresh(); !lastRefresh.IsZero() {
+			details["last_refresh"] = lastRefresh.UTC()
+		}
+
+		if len(countries) == 0 {
+			return details, fmt.Errorf("country cache is empty")
+		}
+		return details, nil
+	}
+}
+
+// DiskCheck reports free space on the filesystem holding dir and fails below minFree bytes
+func DiskCheck(dir string, minFree uint64) CheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		free, total, err := DiskUsage(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		details := map[string]any{
+			"path":        dir,
+			"free_bytes":  free,
+			"total_bytes": total,
+			"min_free":    minFree,
+		}
+
+		if free < minFree {
+			return details, fmt.Errorf("only %d MB free in %s", free/(1024*1024), dir)
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package health
+
+import "errors"
+
+// DiskUsage is not supported on this platform
+func DiskUsage(path string) (free uint64, total uint64, err error) {
+	return 0, 0, errors.New("disk usage is not supported on this platform")
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package health
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DiskUsage returns the bytes available to unprivileged users and the total size of
+// the filesystem holding path
+func DiskUsage(path string) (free uint64, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, fmt.Errorf("error reading disk usage of %s: %w", path, err)
+	}
+
+	blockSize := uint64(stat.Bsize)
+	return uint64(stat.Bavail) * blockSize, uint64(stat.Blocks) * blockSize, nil
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc checks one dependency. Details, if any, are included in verbose reports.
+type CheckFunc func(ctx context.Context) (details map[string]any, err error)
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Name        string         `json:"name"`
+	Status      string         `json:"status"`
+	LatencyMS   float64        `json:"latency_ms"`
+	Error       string         `json:"error,omitempty"`
+	LastError   string         `json:"last_error,omitempty"`
+	LastErrorAt *time.Time     `json:"last_error_at,omitempty"`
+	Details     map[string]any `json:"details,omitempty"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Ready reports whether every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+// check is a registered check and the last error it reported
+type check struct {
+	name        string
+	fn          CheckFunc
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// Checker runs readiness checks concurrently, each with its own timeout
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks []*check
+}
+
+// NewChecker creates a checker whose checks fail after timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check; checks are reported in the order they were added
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, &check{name: name, fn: fn})
+}
+
+// Run runs every check and reports the instance up only if all of them passed
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]*check(nil), c.checks...)
+	c.mu.Unlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk *check) {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, chk)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// runCheck runs one check with the checker's timeout and remembers its last error
+func (c *Checker) runCheck(ctx context.Context, chk *check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		details map[string]any
+		err     error
+	}
+
+	// A hung dependency must not hang the probe, so the check runs in its own goroutine
+	done := make(chan outcome, 1)
+	started := time.Now()
+	go func() {
+		details, err := chk.fn(ctx)
+		done <- outcome{details, err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result.err = fmt.Errorf("check timed out after %s", c.timeout)
+	}
+
+	checkResult := CheckResult{
+		Name:      chk.name,
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(started).Microseconds()) / 1000,
+		Details:   result.details,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if result.err != nil {
+		checkResult.Status = StatusDown
+		checkResult.Error = result.err.Error()
+		chk.lastError = checkResult.Error
+		chk.lastErrorAt = time.Now().UTC()
+	}
+
+	if chk.lastError != "" {
+		lastErrorAt := chk.lastErrorAt
+		checkResult.LastError = chk.lastError
+		checkResult.LastErrorAt = &lastErrorAt
+	}
+
+	return checkResult
+}
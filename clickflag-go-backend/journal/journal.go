@@ -0,0 +1,455 @@
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"clickflag-go-backend/settings"
+)
+
+// Durability controls when journal records are fsynced to disk
+type Durability = settings.JournalDurability
+
+const (
+	DurabilityNone    = settings.DurabilityNone
+	DurabilityBatch   = settings.DurabilityBatch
+	DurabilityRequest = settings.DurabilityRequest
+)
+
+// SyncInterval is how often batched records are fsynced with DurabilityBatch
+const SyncInterval = 10 * time.Millisecond
+
+// Journal is an append-only write-ahead log of accepted clicks.
+// Records are written to numbered segment files next to the configured path.
+// Each flush seals the active segment (Checkpoint) and deletes sealed segments
+// once their counts are committed to the database (Commit).
+//
+// All methods are safe to call on a nil *Journal, which behaves as a disabled journal.
+type Journal struct {
+	path       string
+	durability Durability
+
+	// gate orders appends against checkpoints so that a record and its
+	// in-memory increment always land on the same side of a flush
+	gate sync.RWMutex
+
+	// syncMu serializes fsync with segment rotation
+	syncMu sync.Mutex
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	file         *os.File
+	writer       *bufio.Writer
+	active       uint64   // number of the segment currently written to
+	sealed       []uint64 // segments waiting for Commit
+	activeWrites uint64   // records written to the active segment
+	written      uint64   // sequence number of the last written record
+	synced       uint64   // sequence number of the last fsynced record
+	syncErr      error
+	closed       bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Open opens the journal at path, creating its directory if needed.
+// Segments left over from a previous run are kept sealed until Replay and the next Commit.
+func Open(path string, durability Durability) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating journal directory: %w", err)
+	}
+
+	existing, err := listSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{
+		path:       path,
+		durability: durability,
+		sealed:     existing,
+		done:       make(chan struct{}),
+	}
+	j.cond = sync.NewCond(&j.mu)
+
+	next := uint64(1)
+	if len(existing) > 0 {
+		next = existing[len(existing)-1] + 1
+	}
+
+	if err := j.openSegment(next); err != nil {
+		return nil, err
+	}
+
+	if durability == DurabilityBatch {
+		j.wg.Add(1)
+		go j.syncLoop()
+	}
+
+	slog.Info("Journal opened", "path", path, "durability", durability, "segments", len(existing))
+	return j, nil
+}
+
+// Replay feeds every record from sealed segments to apply, oldest first.
+// A torn record at the end of a segment (from a crash mid-write) is skipped.
+func (j *Journal) Replay(apply func(countryCode string, amount int32)) (int, error) {
+	if j == nil {
+		return 0, nil
+	}
+
+	j.mu.Lock()
+	segments := append([]uint64(nil), j.sealed...)
+	j.mu.Unlock()
+
+	total := 0
+	for _, segment := range segments {
+		count, err := replaySegment(j.segmentPath(segment), apply)
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+
+	if total > 0 {
+		slog.Info("Replayed clicks from journal", "clicks", total, "segments", len(segments))
+	}
+
+	return total, nil
+}
+
+// Append records amount clicks for countryCode and then calls apply, which should
+// add the clicks to pending updates. It returns once the record is as durable as
+// the configured level requires.
+func (j *Journal) Append(countryCode string, amount int32, apply func()) error {
+	if j == nil {
+		apply()
+		return nil
+	}
+
+	j.gate.RLock()
+	seq, err := j.write(countryCode, amount)
+	if err != nil {
+		j.gate.RUnlock()
+		return err
+	}
+	apply()
+	j.gate.RUnlock()
+
+	if j.durability == DurabilityBatch {
+		return j.waitSynced(seq)
+	}
+
+	return nil
+}
+
+// Checkpoint seals the active segment and calls drain while no appends are in flight,
+// so every record in sealed segments is included in what drain collects.
+// The returned checkpoint must be passed to Commit once the drained counts are stored.
+func (j *Journal) Checkpoint(drain func()) (uint64, error) {
+	if j == nil {
+		drain()
+		return 0, nil
+	}
+
+	j.gate.Lock()
+	defer j.gate.Unlock()
+
+	j.syncMu.Lock()
+	defer j.syncMu.Unlock()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return 0, errors.New("journal is closed")
+	}
+
+	// Nothing was written since the last checkpoint, keep using the active segment
+	if j.activeWrites == 0 {
+		drain()
+		var checkpoint uint64
+		if len(j.sealed) > 0 {
+			checkpoint = j.sealed[len(j.sealed)-1]
+		}
+		return checkpoint, nil
+	}
+
+	if err := j.closeSegment(); err != nil {
+		return 0, err
+	}
+
+	sealed := j.active
+	j.sealed = append(j.sealed, sealed)
+
+	if err := j.openSegment(sealed + 1); err != nil {
+		return 0, err
+	}
+
+	drain()
+	return sealed, nil
+}
+
+// Commit deletes sealed segments up to and including checkpoint.
+// Call it only after the counts drained by the matching Checkpoint are durably stored.
+func (j *Journal) Commit(checkpoint uint64) error {
+	if j == nil || checkpoint == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	var remove []uint64
+	remaining := j.sealed[:0]
+	for _, segment := range j.sealed {
+		if segment <= checkpoint {
+			remove = append(remove, segment)
+		} else {
+			remaining = append(remaining, segment)
+		}
+	}
+	j.sealed = remaining
+	j.mu.Unlock()
+
+	for _, segment := range remove {
+		if err := os.Remove(j.segmentPath(segment)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing journal segment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close flushes and closes the active segment
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.closeOnce.Do(func() { close(j.done) })
+	j.wg.Wait()
+
+	j.syncMu.Lock()
+	defer j.syncMu.Unlock()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+
+	err := j.closeSegment()
+	j.cond.Broadcast()
+
+	// An empty active segment carries no records
+	if j.activeWrites == 0 {
+		os.Remove(j.segmentPath(j.active))
+	}
+
+	return err
+}
+
+// write appends a single record to the active segment
+func (j *Journal) write(countryCode string, amount int32) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return 0, errors.New("journal is closed")
+	}
+
+	if _, err := fmt.Fprintf(j.writer, "%s %d\n", countryCode, amount); err != nil {
+		return 0, fmt.Errorf("error writing journal record: %w", err)
+	}
+
+	if err := j.writer.Flush(); err != nil {
+		return 0, fmt.Errorf("error writing journal record: %w", err)
+	}
+
+	if j.durability == DurabilityRequest {
+		if err := j.file.Sync(); err != nil {
+			return 0, fmt.Errorf("error syncing journal: %w", err)
+		}
+	}
+
+	j.activeWrites++
+	j.written++
+	if j.durability == DurabilityRequest {
+		j.synced = j.written
+	}
+
+	return j.written, nil
+}
+
+// waitSynced blocks until the record with the given sequence number is fsynced
+func (j *Journal) waitSynced(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for j.synced < seq && j.syncErr == nil && !j.closed {
+		j.cond.Wait()
+	}
+
+	if j.synced >= seq {
+		return nil
+	}
+	if j.syncErr != nil {
+		return j.syncErr
+	}
+	return errors.New("journal closed before record was synced")
+}
+
+// syncLoop fsyncs batched records every SyncInterval and wakes waiting appends
+func (j *Journal) syncLoop() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.syncBatch()
+		}
+	}
+}
+
+// syncBatch fsyncs everything written so far
+func (j *Journal) syncBatch() {
+	j.syncMu.Lock()
+	defer j.syncMu.Unlock()
+
+	j.mu.Lock()
+	if j.closed || j.synced >= j.written {
+		j.mu.Unlock()
+		return
+	}
+	target := j.written
+	file := j.file
+	j.mu.Unlock()
+
+	// Appends keep writing while the batch is fsynced
+	err := file.Sync()
+
+	j.mu.Lock()
+	if err != nil {
+		j.syncErr = fmt.Errorf("error syncing journal: %w", err)
+	} else {
+		j.syncErr = nil
+		if target > j.synced {
+			j.synced = target
+		}
+	}
+	j.cond.Broadcast()
+	j.mu.Unlock()
+}
+
+// openSegment opens a new active segment; callers must hold mu or own the journal exclusively
+func (j *Journal) openSegment(segment uint64) error {
+	file, err := os.OpenFile(j.segmentPath(segment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening journal segment: %w", err)
+	}
+
+	j.file = file
+	j.writer = bufio.NewWriter(file)
+	j.active = segment
+	j.activeWrites = 0
+	return nil
+}
+
+// closeSegment flushes, fsyncs and closes the active segment; callers must hold mu
+func (j *Journal) closeSegment() error {
+	if err := j.writer.Flush(); err != nil {
+		return fmt.Errorf("error flushing journal segment: %w", err)
+	}
+
+	if j.durability != DurabilityNone {
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("error syncing journal segment: %w", err)
+		}
+		j.synced = j.written
+		j.cond.Broadcast()
+	}
+
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("error closing journal segment: %w", err)
+	}
+
+	return nil
+}
+
+// segmentPath returns the file name of a numbered segment
+func (j *Journal) segmentPath(segment uint64) string {
+	return fmt.Sprintf("%s.%06d", j.path, segment)
+}
+
+// listSegments returns the numbers of existing segments for path, in ascending order
+func listSegments(path string) ([]uint64, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("error listing journal segments: %w", err)
+	}
+
+	var segments []uint64
+	prefix := path + "."
+	for _, match := range matches {
+		segment, err := strconv.ParseUint(strings.TrimPrefix(match, prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	sort.Slice(segments, func(a, b int) bool { return segments[a] < segments[b] })
+	return segments, nil
+}
+
+// replaySegment feeds every valid record of a segment file to apply
+func replaySegment(path string, apply func(countryCode string, amount int32)) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error opening journal segment: %w", err)
+	}
+	defer file.Close()
+
+	total := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			slog.Warn("Skipping malformed journal record", "path", path, "record", scanner.Text())
+			continue
+		}
+
+		amount, err := strconv.ParseInt(fields[1], 10, 32)
+		if err != nil || amount <= 0 {
+			slog.Warn("Skipping malformed journal record", "path", path, "record", scanner.Text())
+			continue
+		}
+
+		apply(fields[0], int32(amount))
+		total += int(amount)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return total, fmt.Errorf("error reading journal segment: %w", err)
+	}
+
+	return total, nil
+}
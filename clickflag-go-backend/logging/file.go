@@ -0,0 +1,353 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"clickflag-go-backend/settings"
+)
+
+// backupTimeFormat is the timestamp appended to rotated file names
+const backupTimeFormat = "2006-01-02-15-04-05"
+
+// RotationInterval is how often the log file is rotated regardless of its size
+type RotationInterval = settings.LogRotation
+
+const (
+	RotateNever  = settings.RotateNever
+	RotateHourly = settings.RotateHourly
+	RotateDaily  = settings.RotateDaily
+)
+
+// periodStart returns the start of the rotation period containing t, in t's location
+func periodStart(i RotationInterval, t time.Time) time.Time {
+	switch i {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return time.Time{}
+	}
+}
+
+// FileWriterOptions configures a FileWriter. Zero limits disable the limit.
+type FileWriterOptions struct {
+	Dir             string           // Directory of the log files, created if missing
+	Filename        string           // Name of the active log file
+	MaxSize         int64            // Rotate once the file would exceed this many bytes
+	Interval        RotationInterval // Also rotate when the hour or day changes
+	Compress        bool             // Gzip rotated files
+	MaxFiles        int              // Rotated files to keep
+	MaxAge          time.Duration    // Remove rotated files older than this
+	CleanupInterval time.Duration    // How often retention is enforced, defaults to one minute
+	Now             func() time.Time // Clock, defaults to time.Now
+}
+
+// FileOptionsForEnvironment returns the size and retention limits used in an environment
+func FileOptionsForEnvironment(environment string) FileWriterOptions {
+	switch environment {
+	case "development":
+		return FileWriterOptions{
+			MaxSize:  5 * 1024 * 1024, // 5MB
+			MaxFiles: 5,               // Keep only 5 log files
+			MaxAge:   30 * 24 * time.Hour,
+		}
+	default:
+		return FileWriterOptions{
+			MaxSize:  10 * 1024 * 1024, // 10MB
+			MaxFiles: 10,               // Keep only 10 log files
+			MaxAge:   7 * 24 * time.Hour,
+		}
+	}
+}
+
+// FileWriter is an io.Writer appending to a log file. It rotates the file by size and
+// by hour or day, gzips rotated files in the background and enforces retention on a ticker.
+// It is safe for concurrent use.
+type FileWriter struct {
+	opts FileWriterOptions
+	path string
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time // Start of the rotation period the active file belongs to
+	closed bool
+
+	background sync.WaitGroup // Compression and cleanup goroutines
+	cleanupMu  sync.Mutex
+	stop       chan struct{}
+}
+
+// NewFileWriter opens the log file and starts the retention ticker
+func NewFileWriter(opts FileWriterOptions) (*FileWriter, error) {
+	if opts.Dir == "" {
+		opts.Dir = "logs"
+	}
+	if opts.Filename == "" {
+		opts.Filename = "app.log"
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = time.Minute
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	w := &FileWriter{
+		opts: opts,
+		path: filepath.Join(opts.Dir, opts.Filename),
+		stop: make(chan struct{}),
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	w.background.Add(1)
+	go w.cleanupLoop()
+
+	return w, nil
+}
+
+// Write appends p to the log file, rotating it first if p would exceed the size
+// limit or the rotation period has ended
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to rotate log: %v\n", err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close stops the retention ticker, waits for pending compressions and closes the file
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.file.Close()
+	w.mu.Unlock()
+
+	close(w.stop)
+	w.background.Wait()
+
+	w.cleanup()
+	return err
+}
+
+// open opens the active log file for appending and records its size and period
+func (w *FileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	// A file left over from an earlier period is rotated on the first write
+	w.file = file
+	w.size = info.Size()
+	w.period = periodStart(w.opts.Interval, w.opts.Now())
+	if w.size > 0 {
+		w.period = periodStart(w.opts.Interval, info.ModTime())
+	}
+	return nil
+}
+
+// shouldRotate reports whether the active file must be rotated before writing n bytes
+func (w *FileWriter) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.Interval != RotateNever && !periodStart(w.opts.Interval, w.opts.Now()).Equal(w.period)
+}
+
+// rotate renames the active file to a timestamped backup and opens a new one.
+// Must be called with mu held.
+func (w *FileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	backupPath := w.backupPath()
+	if err := os.Rename(w.path, backupPath); err != nil {
+		// Keep writing to the old file rather than losing logs
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.background.Add(1)
+	go func() {
+		defer w.background.Done()
+		if w.opts.Compress {
+			if err := compressFile(backupPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to compress rotated log %s: %v\n", backupPath, err)
+			}
+		}
+		w.cleanup()
+	}()
+
+	return nil
+}
+
+// backupPath returns an unused name for the next rotated file
+func (w *FileWriter) backupPath() string {
+	base := w.path + "." + w.opts.Now().Format(backupTimeFormat)
+	path := base
+	for i := 1; fileExists(path) || fileExists(path+".gz"); i++ {
+		path = base + "." + strconv.Itoa(i)
+	}
+	return path
+}
+
+// cleanupLoop enforces retention until the writer is closed
+func (w *FileWriter) cleanupLoop() {
+	defer w.background.Done()
+
+	ticker := time.NewTicker(w.opts.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.cleanup()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// cleanup removes rotated files past the age limit and beyond the count limit
+func (w *FileWriter) cleanup() {
+	w.cleanupMu.Lock()
+	defer w.cleanupMu.Unlock()
+
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		return
+	}
+
+	type rotatedFile struct {
+		path    string
+		modTime time.Time
+	}
+
+	var rotated []rotatedFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, w.opts.Filename+".") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		rotated = append(rotated, rotatedFile{path: filepath.Join(w.opts.Dir, name), modTime: info.ModTime()})
+	}
+
+	// Newest first
+	sort.Slice(rotated, func(a, b int) bool { return rotated[a].modTime.After(rotated[b].modTime) })
+
+	cutoff := w.opts.Now().Add(-w.opts.MaxAge)
+	for i, file := range rotated {
+		tooOld := w.opts.MaxAge > 0 && file.modTime.Before(cutoff)
+		tooMany := w.opts.MaxFiles > 0 && i >= w.opts.MaxFiles
+		if tooOld || tooMany {
+			os.Remove(file.path)
+		}
+	}
+}
+
+// compressFile gzips path into path.gz, keeping its modification time, and removes path
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path+".gz"); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
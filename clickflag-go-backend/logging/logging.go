@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"clickflag-go-backend/settings"
+)
+
+// LevelCritical is for failures that stop the server
+const LevelCritical = settings.LevelCritical
+
+// Options configures the application logger
+type Options struct {
+	Level  string    // debug, info, warn, error or critical
+	Output io.Writer // Defaults to os.Stdout
+}
+
+// defaultLevel is the minimum level of the default logger, changed at runtime by SetLevel
+var defaultLevel = new(slog.LevelVar)
+
+// New creates a JSON logger
+func New(opts Options) (*slog.Logger, error) {
+	level, err := settings.ParseLogLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLogger(opts.Output, level), nil
+}
+
+// newLogger creates a JSON logger writing to output, or os.Stdout
+func newLogger(output io.Writer, level slog.Leveler) *slog.Logger {
+	if output == nil {
+		output = os.Stdout
+	}
+
+	handler := slog.NewJSONHandler(output, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: replaceLevelName,
+	})
+	return slog.New(handler)
+}
+
+// Setup creates the JSON logger and installs it as the process-wide default, so
+// slog calls and the standard log package in every package go through it
+func Setup(opts Options) (*slog.Logger, error) {
+	level, err := settings.ParseLogLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	defaultLevel.Set(level)
+
+	logger := newLogger(opts.Output, defaultLevel)
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// SetLevel changes the minimum level of the default logger, including request loggers derived from it
+func SetLevel(name string) error {
+	level, err := settings.ParseLogLevel(name)
+	if err != nil {
+		return err
+	}
+
+	defaultLevel.Set(level)
+	return nil
+}
+
+// replaceLevelName prints LevelCritical as CRITICAL instead of ERROR+4
+func replaceLevelName(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := attr.Value.Any().(slog.Level); ok && level == LevelCritical {
+			attr.Value = slog.StringValue("CRITICAL")
+		}
+	}
+	return attr
+}
+
+// Critical logs at LevelCritical on the default logger
+func Critical(msg string, args ...any) {
+	slog.Log(context.Background(), LevelCritical, msg, args...)
+}
+
+// contextKey is the context key of the request-scoped logger
+type contextKey struct{}
+
+// WithLogger returns a context carrying a request-scoped logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
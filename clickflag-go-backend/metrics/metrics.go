@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every application metric plus Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ClicksTotal counts accepted clicks per country and submission path (http, batch, websocket)
+	ClicksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clickflag_clicks_total",
+		Help: "Accepted clicks per country.",
+	}, []string{"country", "source"})
+
+	// FlushDuration observes how long writing one batch of pending updates takes
+	FlushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "clickflag_flush_duration_seconds",
+		Help:    "Duration of flushing pending updates to the store.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	// FlushBatchCountries observes how many countries each flushed batch contains
+	FlushBatchCountries = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "clickflag_flush_batch_countries",
+		Help:    "Countries with pending updates per flushed batch.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200},
+	})
+
+	// FlushBatchClicks observes how many clicks each flushed batch contains
+	FlushBatchClicks = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "clickflag_flush_batch_clicks",
+		Help:    "Clicks per flushed batch.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+	})
+
+	// DBErrors counts failed store operations by operation
+	DBErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clickflag_db_errors_total",
+		Help: "Failed database operations.",
+	}, []string{"operation"})
+
+	// RateLimitRejections counts requests or clicks refused by a rate limiter
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clickflag_rate_limit_rejections_total",
+		Help: "Requests or clicks rejected by a rate limiter.",
+	}, []string{"limiter"})
+
+	// HTTPRequestDuration observes request latency by route, method and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clickflag_http_request_duration_seconds",
+		Help:    "HTTP request latency by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ClicksTotal,
+		FlushDuration,
+		FlushBatchCountries,
+		FlushBatchClicks,
+		DBErrors,
+		RateLimitRejections,
+		HTTPRequestDuration,
+	)
+}
+
+// RegisterCacheRefreshAge exports the seconds since the cache was last refreshed
+func RegisterCacheRefreshAge(lastRefresh func() time.Time) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "clickflag_cache_refresh_age_seconds",
+		Help: "Seconds since the country cache was last refreshed from the store.",
+	}, func() float64 {
+		return time.Since(lastRefresh()).Seconds()
+	}))
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
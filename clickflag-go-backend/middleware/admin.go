@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strings"
+
+	"clickflag-go-backend/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminActorKey is the fiber locals key holding the authenticated admin actor
+const AdminActorKey = "admin_actor"
+
+// AdminAuth rejects requests without a valid API key or signed token, sent as
+// "Authorization: Bearer <credential>" or "X-API-Key: <credential>"
+func AdminAuth(authenticator *auth.Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		credential := c.Get("X-API-Key")
+		if header := c.Get(fiber.HeaderAuthorization); credential == "" && len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+			credential = header[7:]
+		}
+
+		actor, err := authenticator.Authenticate(credential)
+		if err != nil {
+			Logger(c).Warn("Rejected admin request", "path", c.Path(), "error", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"success": false,
+				"message": "Unauthorized",
+			})
+		}
+
+		c.Locals(AdminActorKey, actor)
+		return c.Next()
+	}
+}
+
+// GetAdminActor returns the actor set by AdminAuth
+func GetAdminActor(c *fiber.Ctx) string {
+	actor, _ := c.Locals(AdminActorKey).(string)
+	return actor
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"clickflag-go-backend/settings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// clientIPKey is the Locals key of the resolved client IP
+const clientIPKey = "client_ip"
+
+// ClientIPResolver derives the client address of requests that may pass through
+// trusted reverse proxies
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	source  string
+}
+
+// NewClientIPResolver creates a resolver that believes source only when it was added by
+// one of trustedProxies, each an IP address or a CIDR range. With proxy-protocol the
+// listener reads the client address, see proxyproto.Listener.
+func NewClientIPResolver(trustedProxies []string, source string) (*ClientIPResolver, error) {
+	switch source {
+	case settings.ClientIPXForwardedFor, settings.ClientIPXRealIP, settings.ClientIPForwarded, settings.ClientIPProxyProtocol:
+	default:
+		return nil, fmt.Errorf("unknown client IP source %q, expected one of %v", source, settings.ClientIPSources)
+	}
+
+	r := &ClientIPResolver{source: source}
+	for _, proxy := range trustedProxies {
+		prefix, err := settings.ParseTrustedProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		r.trusted = append(r.trusted, prefix)
+	}
+	return r, nil
+}
+
+// Middleware resolves the client IP of the request for ClientIP
+func (r *ClientIPResolver) Middleware(c *fiber.Ctx) error {
+	c.Locals(clientIPKey, r.Resolve(c))
+	return c.Next()
+}
+
+// Resolve returns the connection's address or, when that is a trusted proxy, the
+// rightmost entry of the forwarding header that was not added by a trusted proxy.
+// Entries to its left were sent by the client and cannot be believed.
+func (r *ClientIPResolver) Resolve(c *fiber.Ctx) string {
+	remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return c.IP()
+	}
+
+	// With the PROXY protocol the listener already replaced the connection's address
+	addr := remote.Unmap()
+	if r.source == settings.ClientIPProxyProtocol || !r.IsTrusted(addr) {
+		return addr.String()
+	}
+
+	hops := r.hops(c)
+	for i := len(hops) - 1; i >= 0 && r.IsTrusted(addr); i-- {
+		hop, ok := parseForwardedAddr(hops[i])
+		if !ok {
+			// A malformed entry ends the chain; the proxy that forwarded it is the client
+			break
+		}
+		addr = hop
+	}
+	return addr.String()
+}
+
+// hops returns the addresses in the forwarding header, the nearest proxy's entry last
+func (r *ClientIPResolver) hops(c *fiber.Ctx) []string {
+	header := fiber.HeaderXForwardedFor
+	switch r.source {
+	case settings.ClientIPXRealIP:
+		header = "X-Real-IP"
+	case settings.ClientIPForwarded:
+		header = fiber.HeaderForwarded
+	}
+
+	var hops []string
+	for _, value := range c.Request().Header.PeekAll(header) {
+		for _, element := range strings.Split(string(value), ",") {
+			if r.source == settings.ClientIPForwarded {
+				element = forwardedFor(element)
+			}
+			hops = append(hops, element)
+		}
+	}
+	return hops
+}
+
+// IsTrusted reports whether addr is a trusted proxy
+func (r *ClientIPResolver) IsTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the for= parameter of a Forwarded element, e.g.
+// for="[2001:db8::17]:4711";proto=https, or "" when it has none
+func forwardedFor(element string) string {
+	for _, pair := range strings.Split(element, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && strings.EqualFold(key, "for") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// parseForwardedAddr parses a forwarding header entry, which may carry a port;
+// obfuscated identifiers and "unknown" are not addresses
+func parseForwardedAddr(entry string) (netip.Addr, bool) {
+	entry = strings.TrimSpace(entry)
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]")); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(entry); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// ClientIP returns the client IP resolved by ClientIPResolver, or the connection's
+// address when the resolver is not installed
+func ClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(clientIPKey).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
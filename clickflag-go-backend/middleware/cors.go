@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORSPolicy configures cross-origin requests
+type CORSPolicy struct {
+	Origins       []string // "*", scheme://host or scheme://*.host; empty allows no cross-origin requests
+	Methods       []string
+	Headers       []string
+	ExposeHeaders []string
+	Credentials   bool
+	MaxAge        time.Duration // How long preflight responses may be cached; 0 omits the header
+}
+
+// corsHandlers are the CORS middleware of the standard policy and of public reads
+type corsHandlers struct {
+	standard fiber.Handler
+	read     fiber.Handler // nil when public reads use the standard policy
+}
+
+// SetCORS replaces the CORS policy. Reads of the public read routes allow readOrigins
+// instead, without credentials; empty readOrigins applies policy to them too.
+func (r *Runtime) SetCORS(policy CORSPolicy, readOrigins []string) {
+	handlers := &corsHandlers{standard: newCORS(policy)}
+	if len(readOrigins) > 0 {
+		read := policy
+		read.Origins = readOrigins
+		read.Methods = []string{fiber.MethodGet, fiber.MethodHead}
+		read.Credentials = false
+		handlers.read = newCORS(read)
+	}
+	r.cors.Store(handlers)
+}
+
+// CORS runs the CORS middleware of the request's route
+func (r *Runtime) CORS(c *fiber.Ctx) error {
+	handlers := r.cors.Load()
+	if handlers.read != nil && r.isPublicRead(c) {
+		return handlers.read(c)
+	}
+	return handlers.standard(c)
+}
+
+// isPublicRead reports whether the request, or the request a preflight asks about,
+// reads one of the public read routes
+func (r *Runtime) isPublicRead(c *fiber.Ctx) bool {
+	method := c.Method()
+	if method == fiber.MethodOptions {
+		method = c.Get(fiber.HeaderAccessControlRequestMethod)
+	}
+	if method != fiber.MethodGet && method != fiber.MethodHead {
+		return false
+	}
+
+	for _, pattern := range r.publicReads {
+		if matchRoute(pattern, c.Path()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoute matches a path against a route pattern where ":name" segments match any segment.
+// Like the router, it ignores case and a trailing slash.
+func matchRoute(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return false
+	}
+
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, ":") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if !strings.EqualFold(segment, pathSegments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// newCORS creates the CORS middleware of a policy
+func newCORS(policy CORSPolicy) fiber.Handler {
+	config := cors.Config{
+		AllowMethods:     strings.Join(policy.Methods, ","),
+		AllowHeaders:     strings.Join(policy.Headers, ", "),
+		ExposeHeaders:    strings.Join(policy.ExposeHeaders, ", "),
+		AllowCredentials: policy.Credentials,
+		MaxAge:           int(policy.MaxAge / time.Second),
+	}
+
+	// Without origins the middleware would allow every origin, so reject them all instead
+	if len(policy.Origins) == 0 {
+		config.AllowOriginsFunc = func(string) bool { return false }
+	} else {
+		config.AllowOrigins = strings.Join(policy.Origins, ",")
+	}
+
+	return cors.New(config)
+}
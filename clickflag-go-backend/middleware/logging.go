@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"clickflag-go-backend/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestLogger attaches a logger carrying the request ID, client IP and trace ID to the
+// request context and writes one access log record per request
+func RequestLogger(c *fiber.Ctx) error {
+	start := time.Now()
+
+	attrs := []any{
+		slog.String("request_id", GetRequestID(c)),
+		slog.String("ip", ClientIP(c)),
+	}
+	if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	logger := slog.Default().With(attrs...)
+	c.SetUserContext(logging.WithLogger(c.UserContext(), logger))
+
+	err := c.Next()
+
+	status := responseStatus(c, err)
+
+	level := slog.LevelInfo
+	switch {
+	case status >= fiber.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= fiber.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	logger.LogAttrs(c.UserContext(), level, "HTTP request",
+		slog.String("method", utils.CopyString(c.Method())),
+		slog.String("path", utils.CopyString(c.Path())),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(start)),
+	)
+
+	return err
+}
+
+// Logger returns the request-scoped logger set by RequestLogger
+func Logger(c *fiber.Ctx) *slog.Logger {
+	return logging.FromContext(c.UserContext())
+}
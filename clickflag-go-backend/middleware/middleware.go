@@ -2,85 +2,114 @@ package middleware
 
 import (
 	"context"
-	"os"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"clickflag-go-backend/metrics"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
-// SetupMiddleware sets up all middleware for the application
-func SetupMiddleware(app *fiber.App) {
-	// CORS middleware - Environment'a göre ayarla
-	corsConfig := getCORSConfig()
-	app.Use(cors.New(corsConfig))
-
-	// Rate limiting middleware - 10 requests per second
-	app.Use(limiter.New(limiter.Config{
-		Max:        20,
-		Expiration: 1 * time.Second,
-		KeyGenerator: func(c *fiber.Ctx) string {
-			return c.IP()
-		},
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"success": false,
-				"message": "Rate limit exceeded. Please try again later.",
-				"error":   "TOO_MANY_REQUESTS",
-			})
-		},
-	}))
+// Options configures the middleware of the public API
+type Options struct {
+	ClientIP         *ClientIPResolver // nil uses the connection's address
+	RequestTimeout   time.Duration
+	CORS             CORSPolicy
+	ReadCORSOrigins  []string // Origins of PublicReadRoutes; empty applies CORS to them too
+	PublicReadRoutes []string // Route patterns, e.g. /api/v1/countries/:code/history
+}
 
-	// Logger middleware
-	app.Use(logger.New(logger.Config{
-		Format:     "[${time}] ${status} - ${latency} ${method} ${path}\n",
-		TimeFormat: "2006-01-02 15:04:05",
-		TimeZone:   "Local",
-	}))
+// Runtime swaps the middleware whose settings can change while the server runs
+type Runtime struct {
+	cors        atomic.Pointer[corsHandlers]
+	publicReads []string
+}
+
+// SetupMiddleware sets up all middleware for the application and returns the
+// middleware that can be reconfigured at runtime
+func SetupMiddleware(app *fiber.App, opts Options) *Runtime {
+	runtime := &Runtime{publicReads: opts.PublicReadRoutes}
+	runtime.SetCORS(opts.CORS, opts.ReadCORSOrigins)
+
+	// Metrics middleware - first, so rate-limited and failed requests are measured too
+	app.Use(MetricsMiddleware)
+
+	// Client IP - from X-Forwarded-For only behind trusted proxies
+	if opts.ClientIP == nil {
+		opts.ClientIP = &ClientIPResolver{}
+	}
+	app.Use(opts.ClientIP.Middleware)
+
+	// Request ID, tracing span and structured access log with a request-scoped logger
+	app.Use(RequestID)
+	app.Use(Tracing)
+	app.Use(RequestLogger)
+
+	// CORS middleware - policy comes from the configuration, public reads may use their own origins
+	app.Use(runtime.CORS)
 
 	// Recovery middleware
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
+		StackTraceHandler: func(c *fiber.Ctx, e interface{}) {
+			Logger(c).Error("Panic while handling request", "panic", e, "stack", string(debug.Stack()))
+		},
 	}))
 
 	// Request timeout middleware
 	app.Use(func(c *fiber.Ctx) error {
-		// Set timeout for all requests
-		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		// Set timeout for all requests; the context carries the request's span and logger
+		ctx, cancel := context.WithTimeout(c.UserContext(), opts.RequestTimeout)
 		defer cancel()
 
 		c.Locals("ctx", ctx)
+		c.SetUserContext(ctx)
 		return c.Next()
 	})
+
+	return runtime
+}
+
+// MetricsMiddleware records request latency by route pattern, method and status
+func MetricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	status := responseStatus(c, err)
+
+	// Label by route pattern, not raw path, to keep the number of series bounded
+	route := routePattern(c, status)
+
+	// c.Method() points into a reused buffer, so copy it before it is kept as a label
+	method := utils.CopyString(c.Method())
+	metrics.HTTPRequestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// responseStatus returns the status the response will be sent with
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	// The error handler has not written the response yet
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
 }
 
-// getCORSConfig returns CORS configuration based on environment
-func getCORSConfig() cors.Config {
-	environment := os.Getenv("ENVIRONMENT")
-
-	switch environment {
-	case "production":
-		return cors.Config{
-			AllowOrigins: "https://clickflag.com,https://www.clickflag.com",
-			AllowMethods: "GET,POST,OPTIONS",
-			AllowHeaders: "Origin, Content-Type, Accept",
-		}
-	case "staging":
-		return cors.Config{
-			AllowOrigins: "https://staging.clickflag.com,https://clickflag.com",
-			AllowMethods: "GET,POST,OPTIONS",
-			AllowHeaders: "Origin, Content-Type, Accept",
-		}
-	default: // development
-		return cors.Config{
-			AllowOrigins: "http://localhost:3000,http://localhost:8080,http://127.0.0.1:3000",
-			AllowMethods: "GET,POST,OPTIONS",
-			AllowHeaders: "Origin, Content-Type, Accept",
-		}
+// routePattern returns the matched route pattern, or "unmatched" when no route matched
+func routePattern(c *fiber.Ctx, status int) string {
+	route := c.Route().Path
+	if status == fiber.StatusNotFound && route == "/" && c.Path() != "/" {
+		return "unmatched"
 	}
+	return route
 }
 
 // HealthCheckMiddleware adds health check headers
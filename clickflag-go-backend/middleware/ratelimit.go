@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"clickflag-go-backend/metrics"
+	"clickflag-go-backend/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReadRateLimit limits GET and HEAD requests per client IP; other methods pass through
+// to the limits of their handlers
+func ReadRateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+
+		decision := limiter.Allow(ClientIP(c), "")
+		SetRateLimitHeaders(c, decision)
+		if !decision.Allowed {
+			return TooManyRequests(c, "read")
+		}
+		return c.Next()
+	}
+}
+
+// SetRateLimitHeaders reports a rate limit decision in X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset (seconds until the limit is fully
+// restored), and in Retry-After when the request was refused
+func SetRateLimitHeaders(c *fiber.Ctx, decision ratelimit.Decision) {
+	c.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	if !decision.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(ceilSeconds(decision.RetryAfter), 1)))
+	}
+}
+
+// TooManyRequests sends the response of a request refused by the named limiter
+func TooManyRequests(c *fiber.Ctx, limiter string) error {
+	metrics.RateLimitRejections.WithLabelValues(limiter).Inc()
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"success": false,
+		"message": "Rate limit exceeded. Please try again later.",
+		"error":   "TOO_MANY_REQUESTS",
+	})
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
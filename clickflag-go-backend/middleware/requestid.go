@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// RequestIDKey is the fiber locals key holding the request ID
+const RequestIDKey = "requestid"
+
+// maxRequestIDLength bounds accepted X-Request-ID values
+const maxRequestIDLength = 128
+
+// RequestID accepts the client's X-Request-ID, or generates one when it is missing or
+// malformed, stores it in fiber locals and returns it on the response
+func RequestID(c *fiber.Ctx) error {
+	id := c.Get(fiber.HeaderXRequestID)
+	if !validRequestID(id) {
+		id = utils.UUIDv4()
+	} else {
+		// The header value points into a reused buffer
+		id = utils.CopyString(id)
+	}
+
+	c.Locals(RequestIDKey, id)
+	c.Set(fiber.HeaderXRequestID, id)
+	return c.Next()
+}
+
+// GetRequestID returns the ID set by RequestID, or an empty string
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(RequestIDKey).(string)
+	return id
+}
+
+// validRequestID accepts IDs of letters, digits and -_.: so they are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
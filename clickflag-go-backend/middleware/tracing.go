@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"clickflag-go-backend/tracing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span per request, continuing the caller's trace from the
+// traceparent header, and makes it the parent of spans started by handlers
+func Tracing(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+
+	method := utils.CopyString(c.Method())
+	ctx, span := tracing.Tracer().Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("url.path", utils.CopyString(c.Path())),
+			attribute.String("client.address", ClientIP(c)),
+			attribute.String("http.request_id", GetRequestID(c)),
+		),
+	)
+	defer span.End()
+
+	c.SetUserContext(ctx)
+	err := c.Next()
+
+	status := responseStatus(c, err)
+	route := routePattern(c, status)
+	span.SetName(method + " " + route)
+	span.SetAttributes(
+		attribute.String("http.route", route),
+		attribute.Int("http.response.status_code", status),
+	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, utils.StatusMessage(status))
+	}
+
+	return err
+}
+
+// headerCarrier adapts fiber request and response headers to the propagation API
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+// Get returns a request header
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+// Set sets a response header
+func (h headerCarrier) Set(key, value string) {
+	h.c.Set(key, value)
+}
+
+// Keys lists the request header names
+func (h headerCarrier) Keys() []string {
+	keys := []string{}
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
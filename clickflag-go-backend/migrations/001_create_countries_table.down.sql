@@ -0,0 +1,4 @@
+-- Rollback 001: Drop countries table
+
+DROP INDEX IF EXISTS idx_countries_country_code;
+DROP TABLE IF EXISTS countries;
@@ -1,7 +1,5 @@
 -- Migration 002: Replace country code 'TW' with 'SS' and update CHECK constraint
--- Safe table rebuild pattern for SQLite
-
-BEGIN IMMEDIATE;
+-- Safe table rebuild pattern for SQLite (the migration runner wraps it in a transaction)
 
 -- 1) Yeni tablo: CHECK listesi 'TW' çıkarıldı, 'SS' eklendi
 CREATE TABLE IF NOT EXISTS countries_new (
@@ -27,5 +25,3 @@ ALTER TABLE countries_new RENAME TO countries;
 
 -- 4) İndeksi yeniden oluştur
 CREATE INDEX IF NOT EXISTS idx_countries_country_code ON countries(country_code);
-
-COMMIT;
\ No newline at end of file
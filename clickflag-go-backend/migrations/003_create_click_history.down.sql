@@ -0,0 +1,5 @@
+-- Rollback 003: Drop click history tables
+
+DROP TABLE IF EXISTS country_clicks_minute;
+DROP TABLE IF EXISTS country_clicks_hour;
+DROP TABLE IF EXISTS country_clicks_day;
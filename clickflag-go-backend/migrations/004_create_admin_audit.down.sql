@@ -0,0 +1,4 @@
+-- Rollback 004: Drop admin audit log and frozen countries
+
+DROP TABLE IF EXISTS frozen_countries;
+DROP TABLE IF EXISTS admin_audit;
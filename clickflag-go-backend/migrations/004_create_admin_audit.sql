@@ -0,0 +1,21 @@
+-- Migration 004: Admin audit log and frozen countries
+-- created_at and frozen_at are Unix milliseconds (UTC)
+
+CREATE TABLE IF NOT EXISTS admin_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    country_code VARCHAR(3),
+    before_value INTEGER,
+    after_value INTEGER,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_created_at ON admin_audit(created_at);
+
+CREATE TABLE IF NOT EXISTS frozen_countries (
+    country_code VARCHAR(3) PRIMARY KEY,
+    frozen_by TEXT NOT NULL,
+    frozen_at INTEGER NOT NULL
+);
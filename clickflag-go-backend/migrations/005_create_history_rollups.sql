@@ -0,0 +1,7 @@
+-- Migration 005: Rollup watermark of the hourly and daily history tables
+-- rolled_up_to is the Unix second (UTC) of the first bucket not rolled up yet
+
+CREATE TABLE IF NOT EXISTS history_rollups (
+    target_interval VARCHAR(10) PRIMARY KEY,
+    rolled_up_to INTEGER NOT NULL
+);
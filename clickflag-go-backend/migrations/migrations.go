@@ -0,0 +1,27 @@
+// Package migrations embeds the SQL migration files into the binary
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS contains every NNN_*.sql migration and rollback file for SQLite
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed postgres/*.sql
+var postgresFiles embed.FS
+
+// PostgresFS contains the PostgreSQL migrations from the postgres directory
+var PostgresFS = mustSub(postgresFiles, "postgres")
+
+// mustSub returns the subtree of an embedded directory
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
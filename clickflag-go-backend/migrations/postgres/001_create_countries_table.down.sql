@@ -0,0 +1,3 @@
+-- Rollback 001 (PostgreSQL): Drop countries table
+
+DROP TABLE IF EXISTS countries;
@@ -0,0 +1,29 @@
+-- Migration 002: Time-bucketed click history
+-- Buckets are Unix seconds (UTC) at the start of the minute, hour or day
+
+CREATE TABLE IF NOT EXISTS country_clicks_minute (
+    country_code VARCHAR(3) NOT NULL,
+    bucket BIGINT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (country_code, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_country_clicks_minute_bucket ON country_clicks_minute(bucket);
+
+CREATE TABLE IF NOT EXISTS country_clicks_hour (
+    country_code VARCHAR(3) NOT NULL,
+    bucket BIGINT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (country_code, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_country_clicks_hour_bucket ON country_clicks_hour(bucket);
+
+CREATE TABLE IF NOT EXISTS country_clicks_day (
+    country_code VARCHAR(3) NOT NULL,
+    bucket BIGINT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (country_code, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_country_clicks_day_bucket ON country_clicks_day(bucket);
@@ -0,0 +1,3 @@
+-- Rollback 004: Drop the history rollup watermarks
+
+DROP TABLE IF EXISTS history_rollups;
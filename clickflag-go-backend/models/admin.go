@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Admin actions recorded in the audit log
+const (
+	AuditSetValue    = "set_value"
+	AuditAdjustValue = "adjust_value"
+	AuditResetCounts = "reset_counts"
+	AuditFreeze      = "freeze"
+	AuditUnfreeze    = "unfreeze"
+	AuditFlush       = "flush"
+	AuditRefresh     = "refresh"
+	AuditReload      = "reload_config"
+)
+
+// AuditEntry is one admin action. Before and After are set for actions that change a count.
+type AuditEntry struct {
+	ID          int64     `json:"id"`
+	Actor       string    `json:"actor"`
+	Action      string    `json:"action"`
+	CountryCode string    `json:"country_code,omitempty"`
+	Before      *int      `json:"before,omitempty"`
+	After       *int      `json:"after,omitempty"`
+	Detail      string    `json:"detail,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AdminValueRequest sets (value) or adjusts (delta) a country's count
+type AdminValueRequest struct {
+	Value *int `json:"value"`
+	Delta *int `json:"delta"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "clickflag-go-backend/constants"
+import (
+	"time"
+
+	"clickflag-go-backend/constants"
+)
 
 // Country represents a country code with its associated value
 type Country struct {
@@ -24,6 +28,7 @@ type CountryRequest struct {
 type CountryResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	Version uint64 `json:"version,omitempty"` // Snapshot version of country counts, for ?since=
 	Data    any    `json:"data,omitempty"`
 }
 
@@ -31,3 +36,33 @@ type CountryResponse struct {
 func IsValidCountryCode(code string) bool {
 	return constants.IsValidCountryCode(code)
 }
+
+// HistoryPoint represents the clicks a country received in one time bucket
+type HistoryPoint struct {
+	CountryCode string    `json:"country_code"`
+	Bucket      time.Time `json:"bucket"`
+	Clicks      int64     `json:"clicks"`
+}
+
+// HistoryBucket represents the clicks received in one time bucket of a series
+type HistoryBucket struct {
+	Bucket time.Time `json:"bucket"`
+	Clicks int64     `json:"clicks"`
+}
+
+// CountryHistory represents a country's click time series
+type CountryHistory struct {
+	CountryCode string          `json:"country_code"`
+	Interval    string          `json:"interval"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	Total       int64           `json:"total"`
+	Points      []HistoryBucket `json:"points"`
+}
+
+// BatchItemResult reports whether the clicks for one country of a batch were accepted
+type BatchItemResult struct {
+	Clicks int    `json:"clicks"`
+	Status string `json:"status"` // accepted or rejected
+	Error  string `json:"error,omitempty"`
+}
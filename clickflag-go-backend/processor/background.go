@@ -2,38 +2,152 @@ package processor
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"clickflag-go-backend/cache"
 	"clickflag-go-backend/database"
+	"clickflag-go-backend/journal"
+	"clickflag-go-backend/metrics"
+	"clickflag-go-backend/stream"
+	"clickflag-go-backend/tracing"
 
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // BackgroundProcessor handles background processing tasks
 type BackgroundProcessor struct {
-	cache    *cache.Cache
-	cronExpr string
-	ctx      context.Context
-	cancel   context.CancelFunc
-	cron     *cron.Cron
+	cache        *cache.Cache
+	store        database.Store
+	journal      *journal.Journal
+	broadcaster  *stream.Broadcaster
+	recoveryPath string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	flushMu      sync.Mutex
+
+	// recoveryPending is set while replayed recovery counts are not flushed yet; guarded by flushMu
+	recoveryPending bool
+
+	// refreshMu serializes cache refreshes of Flush and the admin API, so an older load
+	// never replaces a newer one and changes are published in order
+	refreshMu sync.Mutex
+
+	scheduleMu sync.Mutex
+	cronExpr   string
+	cron       *cron.Cron
+	entryID    cron.EntryID
+
+	statusMu    sync.Mutex
+	lastSuccess time.Time
+	lastErr     error
+	lastErrAt   time.Time
 }
 
-// NewBackgroundProcessor creates a new background processor
-func NewBackgroundProcessor(cache *cache.Cache, cronExpression string) *BackgroundProcessor {
+// NewBackgroundProcessor creates a new background processor that flushes pending updates to store.
+// The journal may be nil when click journaling is disabled.
+// Counts that cannot be flushed on shutdown are spilled to recoveryPath.
+func NewBackgroundProcessor(cache *cache.Cache, store database.Store, clickJournal *journal.Journal, cronExpression string, recoveryPath string) *BackgroundProcessor {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &BackgroundProcessor{
-		cache:    cache,
-		cronExpr: cronExpression,
-		ctx:      ctx,
-		cancel:   cancel,
+	bp := &BackgroundProcessor{
+		cache:        cache,
+		store:        store,
+		journal:      clickJournal,
+		cronExpr:     cronExpression,
+		recoveryPath: recoveryPath,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	// Counts as flushed at startup, so a fresh instance is not reported stuck
+	bp.lastSuccess = time.Now()
+	return bp
+}
+
+// FlushStatus describes the outcome of recent flushes
+type FlushStatus struct {
+	LastSuccess time.Time // When a flush last succeeded, or the processor was created
+	LastError   error     // Error of the most recent flush, nil if it succeeded
+	LastErrorAt time.Time
+}
+
+// FlushStatus reports when a flush last succeeded and whether the most recent one failed
+func (bp *BackgroundProcessor) FlushStatus() FlushStatus {
+	bp.statusMu.Lock()
+	defer bp.statusMu.Unlock()
+
+	return FlushStatus{
+		LastSuccess: bp.lastSuccess,
+		LastError:   bp.lastErr,
+		LastErrorAt: bp.lastErrAt,
+	}
+}
+
+// recordFlush remembers the outcome of a flush for health checks
+func (bp *BackgroundProcessor) recordFlush(err error) {
+	bp.statusMu.Lock()
+	defer bp.statusMu.Unlock()
+
+	if err != nil {
+		bp.lastErr = err
+		bp.lastErrAt = time.Now()
+		return
+	}
+
+	bp.lastErr = nil
+	bp.lastSuccess = time.Now()
+}
+
+// SetBroadcaster publishes the countries changed by each cache refresh to broadcaster
+func (bp *BackgroundProcessor) SetBroadcaster(broadcaster *stream.Broadcaster) {
+	bp.broadcaster = broadcaster
+}
+
+// ReplayRecovery loads counts spilled by a previous shutdown into pending updates. The
+// recovery file is removed by the first flush that writes them, so a crash before then
+// replays it again. It returns the number of clicks replayed.
+func (bp *BackgroundProcessor) ReplayRecovery() (int, error) {
+	if bp.recoveryPath == "" {
+		return 0, nil
+	}
+
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
+
+	replayed, err := ReplayRecoveryFile(bp.recoveryPath, bp.cache)
+	if replayed > 0 {
+		bp.recoveryPending = true
+	}
+	return replayed, err
+}
+
+// commitRecovery removes the recovery file once a flush wrote its counts; the caller holds flushMu
+func (bp *BackgroundProcessor) commitRecovery() {
+	if !bp.recoveryPending {
+		return
+	}
+
+	if err := os.Remove(bp.recoveryPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("Error removing replayed recovery file", "path", bp.recoveryPath, "error", err)
+		return
 	}
+	bp.recoveryPending = false
 }
 
 // Start starts the background processor
 func (bp *BackgroundProcessor) Start() {
-	log.Printf("Starting background processor with cron expression: %s", bp.cronExpr)
+	bp.scheduleMu.Lock()
+	defer bp.scheduleMu.Unlock()
+
+	slog.Info("Starting background processor", "cron", bp.cronExpr)
 
 	// Create new cron instance with seconds enabled
 	bp.cron = cron.New(cron.WithSeconds())
@@ -41,11 +155,12 @@ func (bp *BackgroundProcessor) Start() {
 	// Add the job to cron
 	entryID, err := bp.cron.AddFunc(bp.cronExpr, bp.processPendingUpdates)
 	if err != nil {
-		log.Printf("Error adding cron job: %v", err)
+		slog.Error("Error adding cron job", "error", err)
 		return
 	}
+	bp.entryID = entryID
 
-	log.Printf("Cron job added with entry ID: %d", entryID)
+	slog.Debug("Cron job added", "entry_id", entryID)
 
 	// Process immediately on start
 	bp.processPendingUpdates()
@@ -53,59 +168,272 @@ func (bp *BackgroundProcessor) Start() {
 	// Start the cron scheduler
 	bp.cron.Start()
 
-	log.Println("Background processor started successfully")
+	slog.Info("Background processor started successfully")
+}
+
+// SetSchedule replaces the cron expression of flushes; a running scheduler switches
+// over without losing pending updates
+func (bp *BackgroundProcessor) SetSchedule(cronExpression string) error {
+	bp.scheduleMu.Lock()
+	defer bp.scheduleMu.Unlock()
+
+	if bp.cron == nil {
+		bp.cronExpr = cronExpression
+		return nil
+	}
+
+	// Add the new entry before removing the old one, so flushes never stop
+	entryID, err := bp.cron.AddFunc(cronExpression, bp.processPendingUpdates)
+	if err != nil {
+		return fmt.Errorf("error scheduling flushes with %q: %w", cronExpression, err)
+	}
+	bp.cron.Remove(bp.entryID)
+
+	slog.Info("Flush schedule changed", "old_cron", bp.cronExpr, "cron", cronExpression)
+	bp.entryID = entryID
+	bp.cronExpr = cronExpression
+	return nil
+}
+
+// Schedule returns the cron expression of flushes
+func (bp *BackgroundProcessor) Schedule() string {
+	bp.scheduleMu.Lock()
+	defer bp.scheduleMu.Unlock()
+
+	return bp.cronExpr
 }
 
 // Stop stops the background processor
 func (bp *BackgroundProcessor) Stop() {
-	log.Println("Stopping background processor...")
+	slog.Info("Stopping background processor")
+
+	bp.scheduleMu.Lock()
+	defer bp.scheduleMu.Unlock()
 
 	if bp.cron != nil {
 		// Stop the cron scheduler
 		ctx := bp.cron.Stop()
 		<-ctx.Done()
-		log.Println("Cron scheduler stopped")
+		slog.Debug("Cron scheduler stopped")
 	}
 
 	bp.cancel()
-	log.Println("Background processor stopped")
+	slog.Info("Background processor stopped")
 }
 
-// processPendingUpdates processes all pending country code updates
-func (bp *BackgroundProcessor) processPendingUpdates() {
-	// Get pending updates from cache
-	pendingUpdates := bp.cache.GetPendingUpdates()
+// FlushResult reports the outcome of writing one batch of pending updates
+type FlushResult struct {
+	Applied map[string]int32 // Counts written to the store
+	Retried map[string]int32 // Counts put back into pending updates after a failed write
+	Dropped map[string]int32 // Counts discarded because the country does not exist in the store
+}
+
+// RetriedCountries returns the sorted country codes that were put back for retry
+func (r FlushResult) RetriedCountries() []string {
+	codes := make([]string, 0, len(r.Retried))
+	for code := range r.Retried {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Shutdown stops the background processor and performs a final flush of pending updates.
+// Counts that cannot be written before ctx is done are spilled to the recovery file
+// so they can be replayed on the next boot.
+func (bp *BackgroundProcessor) Shutdown(ctx context.Context) error {
+	// Stop the scheduler first so no cron run races with the final flush
+	bp.Stop()
+
+	result, err := bp.Flush(ctx)
+	if err == nil {
+		slog.Info("Final flush completed successfully", "countries", len(result.Applied))
+		return nil
+	}
+
+	slog.Error("Final flush failed", "error", err)
+
+	// The failed batch was put back into pending updates; drain it again for the recovery file
+	pendingUpdates, checkpoint, err := bp.drainPendingUpdates()
+	if err != nil {
+		return fmt.Errorf("error draining pending updates: %w", err)
+	}
 
 	if len(pendingUpdates) == 0 {
-		log.Println("No pending updates to process")
-		return
+		return bp.journal.Commit(checkpoint)
+	}
+
+	// Without a recovery file the journal segments are kept and replayed on the next boot
+	if bp.recoveryPath == "" {
+		return fmt.Errorf("final flush failed for %d countries and no recovery file is configured", len(pendingUpdates))
+	}
+
+	// Replayed counts that were never flushed are part of pendingUpdates, so they replace the file
+	bp.flushMu.Lock()
+	save := SaveRecoveryFile
+	if bp.recoveryPending {
+		save = writeRecoveryFile
+	}
+	bp.flushMu.Unlock()
+
+	if err := save(bp.recoveryPath, pendingUpdates); err != nil {
+		return fmt.Errorf("final flush failed for %d countries and could not be spilled: %w", len(pendingUpdates), err)
 	}
 
-	log.Printf("Processing %d pending updates", len(pendingUpdates))
+	slog.Warn("Spilled pending updates to recovery file", "countries", len(pendingUpdates), "path", bp.recoveryPath)
 
-	// Process each pending update
-	for countryCode, count := range pendingUpdates {
-		log.Printf("Processing %d updates for country code: %s", count, countryCode)
+	// The recovery file now owns the failed counts, so the journal must not replay them again
+	return bp.journal.Commit(checkpoint)
+}
+
+// Flush writes all pending updates to the store in one batch and refreshes the cache.
+// If the batch cannot be written, its counts are put back into pending updates so no
+// clicks are lost, and the returned result lists the retried countries.
+func (bp *BackgroundProcessor) Flush(ctx context.Context) (result FlushResult, err error) {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
+	defer func() { bp.recordFlush(err) }()
+
+	result = FlushResult{
+		Applied: map[string]int32{},
+		Retried: map[string]int32{},
+		Dropped: map[string]int32{},
+	}
+
+	pendingUpdates, checkpoint, err := bp.drainPendingUpdates()
+	if err != nil {
+		return result, fmt.Errorf("error checkpointing journal: %w", err)
+	}
 
-		// Increment the value in database by the total count (more efficient)
-		if err := database.IncrementCountryValueBy(countryCode, count); err != nil {
-			log.Printf("Error incrementing value for country %s: %v", countryCode, err)
-			continue
+	if len(pendingUpdates) == 0 {
+		if err := bp.journal.Commit(checkpoint); err != nil {
+			slog.Error("Error committing journal", "error", err)
+		}
+		bp.commitRecovery()
+		return result, nil
+	}
+
+	clicks := sumClicks(pendingUpdates)
+	metrics.FlushBatchCountries.Observe(float64(len(pendingUpdates)))
+	metrics.FlushBatchClicks.Observe(float64(clicks))
+
+	// Only flushes with work are traced, so idle ticks do not flood the exporter
+	ctx, span := tracing.Tracer().Start(ctx, "Flush", trace.WithAttributes(
+		attribute.Int("flush.countries", len(pendingUpdates)),
+		attribute.Int64("flush.clicks", clicks),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	started := time.Now()
+	err = bp.store.ApplyIncrements(ctx, pendingUpdates, started.UTC())
+	metrics.FlushDuration.Observe(time.Since(started).Seconds())
+
+	if err != nil {
+		metrics.DBErrors.WithLabelValues("apply_increments").Inc()
+		var incrementErr *database.IncrementError
+		errors.As(err, &incrementErr)
+
+		for countryCode, count := range pendingUpdates {
+			// Retrying a country the store does not know would fail forever
+			if incrementErr != nil && errors.Is(incrementErr.Failed[countryCode], database.ErrCountryNotFound) {
+				result.Dropped[countryCode] = count
+				continue
+			}
+
+			bp.cache.AddPendingUpdateBy(countryCode, count)
+			result.Retried[countryCode] = count
 		}
+
+		// The journal is not committed, so the retried clicks stay recorded until a later flush succeeds
+		return result, fmt.Errorf("error applying pending updates: %w", err)
+	}
+
+	result.Applied = pendingUpdates
+
+	// Journal records up to the checkpoint, and replayed recovery counts, are now reflected in the store
+	if err := bp.journal.Commit(checkpoint); err != nil {
+		slog.Error("Error committing journal", "error", err)
 	}
+	bp.commitRecovery()
 
 	// Refresh cache with updated data
-	bp.refreshCache()
+	if err := bp.RefreshCache(ctx); err != nil {
+		slog.Error("Error refreshing cache", "error", err)
+	}
+
+	return result, nil
+}
+
+// drainPendingUpdates swaps out pending updates together with a journal checkpoint
+// covering exactly the drained clicks
+func (bp *BackgroundProcessor) drainPendingUpdates() (map[string]int32, uint64, error) {
+	var pendingUpdates map[string]int32
+	checkpoint, err := bp.journal.Checkpoint(func() {
+		pendingUpdates = bp.cache.GetPendingUpdates()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return pendingUpdates, checkpoint, nil
 }
 
-// refreshCache refreshes the cache with fresh data from database
-func (bp *BackgroundProcessor) refreshCache() {
-	countries, err := database.GetAllCountries()
+// processPendingUpdates processes all pending country code updates
+func (bp *BackgroundProcessor) processPendingUpdates() {
+	result, err := bp.Flush(bp.ctx)
 	if err != nil {
-		log.Printf("Error refreshing cache: %v", err)
+		slog.Error("Error flushing pending updates", "error", err)
+		if len(result.Retried) > 0 {
+			slog.Warn("Retrying countries on the next flush", "countries", len(result.Retried), "country_codes", result.RetriedCountries())
+		}
+		for countryCode, count := range result.Dropped {
+			slog.Warn("Dropped updates for unknown country", "country_code", countryCode, "clicks", count)
+		}
+		return
+	}
+
+	if len(result.Applied) == 0 {
+		slog.Debug("No pending updates to process")
 		return
 	}
 
-	bp.cache.RefreshCountries(countries)
-	log.Printf("Cache refreshed with %d countries", len(countries))
+	slog.Info("Processed pending updates", "countries", len(result.Applied))
+}
+
+// RefreshCache reloads the cache from the store and publishes the changed countries
+func (bp *BackgroundProcessor) RefreshCache(ctx context.Context) error {
+	bp.refreshMu.Lock()
+	defer bp.refreshMu.Unlock()
+
+	countries, err := bp.store.LoadCountries(ctx)
+	if err != nil {
+		metrics.DBErrors.WithLabelValues("load_countries").Inc()
+		return fmt.Errorf("error loading countries: %w", err)
+	}
+
+	deltas := bp.cache.RefreshCountries(countries)
+	slog.Debug("Cache refreshed", "countries", len(countries), "changed", len(deltas))
+
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	// Push only the changed countries to live stream subscribers
+	counts := make(map[string]int, len(deltas))
+	for _, country := range countries {
+		if _, changed := deltas[country.CountryCode]; changed {
+			counts[country.CountryCode] = country.Value
+		}
+	}
+	bp.broadcaster.Publish(counts, deltas)
+	return nil
+}
+
+// sumClicks returns the total number of clicks in a batch
+func sumClicks(batch map[string]int32) int64 {
+	var total int64
+	for _, count := range batch {
+		total += int64(count)
+	}
+	return total
 }
@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/metrics"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Rollups recompute the previous hour and day from the finer table, so the finer
+// table has to keep at least that much history
+const (
+	minMinuteRetention = 2 * time.Hour
+	minHourRetention   = 48 * time.Hour
+)
+
+// HistoryMaintainer periodically rolls minute history up into hours and days and
+// prunes buckets past their retention
+type HistoryMaintainer struct {
+	store     database.Store
+	retention database.RetentionPolicy
+	cronExpr  string
+	cron      *cron.Cron
+}
+
+// NewHistoryMaintainer creates a history maintainer running on cronExpression.
+// Retentions shorter than the rollup window are raised to the minimum.
+func NewHistoryMaintainer(store database.Store, retention database.RetentionPolicy, cronExpression string) *HistoryMaintainer {
+	if retention.Minute > 0 && retention.Minute < minMinuteRetention {
+		slog.Warn("Minute history retention is below the rollup window", "retention", retention.Minute, "using", minMinuteRetention)
+		retention.Minute = minMinuteRetention
+	}
+	if retention.Hour > 0 && retention.Hour < minHourRetention {
+		slog.Warn("Hourly history retention is below the rollup window", "retention", retention.Hour, "using", minHourRetention)
+		retention.Hour = minHourRetention
+	}
+
+	return &HistoryMaintainer{
+		store:     store,
+		retention: retention,
+		cronExpr:  cronExpression,
+	}
+}
+
+// Start schedules the rollup and prune job
+func (hm *HistoryMaintainer) Start() {
+	slog.Info("Starting history maintainer", "cron", hm.cronExpr)
+
+	hm.cron = cron.New(cron.WithSeconds())
+	if _, err := hm.cron.AddFunc(hm.cronExpr, hm.run); err != nil {
+		slog.Error("Error adding history cron job", "error", err)
+		return
+	}
+
+	hm.cron.Start()
+}
+
+// Stop stops the scheduler and waits for a running job to finish
+func (hm *HistoryMaintainer) Stop() {
+	if hm.cron == nil {
+		return
+	}
+
+	<-hm.cron.Stop().Done()
+	slog.Info("History maintainer stopped")
+}
+
+// Run rolls up and prunes history once
+func (hm *HistoryMaintainer) Run(ctx context.Context, now time.Time) error {
+	if err := hm.store.RollupHistory(ctx, now); err != nil {
+		metrics.DBErrors.WithLabelValues("rollup_history").Inc()
+		return err
+	}
+
+	if err := hm.store.PruneHistory(ctx, hm.retention, now); err != nil {
+		metrics.DBErrors.WithLabelValues("prune_history").Inc()
+		return err
+	}
+
+	return nil
+}
+
+// run is the cron job entry point
+func (hm *HistoryMaintainer) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := hm.Run(ctx, time.Now().UTC()); err != nil {
+		slog.Error("Error maintaining click history", "error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"clickflag-go-backend/cache"
+)
+
+// recoverySnapshot is the on-disk format of the recovery file
+type recoverySnapshot struct {
+	SavedAt time.Time        `json:"saved_at"`
+	Pending map[string]int32 `json:"pending"`
+}
+
+// SaveRecoveryFile writes pending counts that could not be flushed to the recovery file.
+// Counts already present in the file are merged so nothing from a previous spill is lost.
+func SaveRecoveryFile(path string, pending map[string]int32) error {
+	existing, err := LoadRecoveryFile(path)
+	if err != nil {
+		return err
+	}
+
+	merged := make(map[string]int32, len(existing)+len(pending))
+	for code, count := range existing {
+		merged[code] += count
+	}
+	for code, count := range pending {
+		merged[code] += count
+	}
+
+	return writeRecoveryFile(path, merged)
+}
+
+// writeRecoveryFile replaces the recovery file with pending
+func writeRecoveryFile(path string, pending map[string]int32) error {
+	data, err := json.Marshal(recoverySnapshot{
+		SavedAt: time.Now().UTC(),
+		Pending: pending,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding recovery file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating recovery directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a half-written recovery file
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating recovery file: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing recovery file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error syncing recovery file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing recovery file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error renaming recovery file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadRecoveryFile reads pending counts from the recovery file.
+// A missing file is not an error and yields an empty map.
+func LoadRecoveryFile(path string) (map[string]int32, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]int32{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading recovery file: %w", err)
+	}
+
+	var snapshot recoverySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding recovery file: %w", err)
+	}
+
+	if snapshot.Pending == nil {
+		snapshot.Pending = map[string]int32{}
+	}
+
+	return snapshot.Pending, nil
+}
+
+// ReplayRecoveryFile loads counts spilled by a previous shutdown back into pending updates.
+// The file is kept, since the counts are only in memory until a flush writes them; see
+// BackgroundProcessor.ReplayRecovery. It returns the number of clicks replayed.
+func ReplayRecoveryFile(path string, c *cache.Cache) (int, error) {
+	pending, err := LoadRecoveryFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	total := 0
+	for code, count := range pending {
+		if count <= 0 {
+			continue
+		}
+		c.AddPendingUpdateBy(code, count)
+		total += int(count)
+	}
+
+	slog.Info("Replayed clicks from recovery file", "clicks", total, "countries", len(pending), "path", path)
+	return total, nil
+}
@@ -0,0 +1,203 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// v2Signature starts every PROXY protocol v2 header
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxV1Length is the longest v1 header, including CRLF
+const maxV1Length = 107
+
+// Listener accepts connections that start with a PROXY protocol v1 or v2 header, as sent by
+// load balancers such as HAProxy or AWS NLB, and reports the client address it carries
+// as the connection's remote address
+type Listener struct {
+	net.Listener
+	trusted func(netip.Addr) bool
+	timeout time.Duration
+}
+
+// NewListener wraps inner. Peers for which trusted returns true must send a header
+// within timeout; other peers are served without one, under their own address.
+func NewListener(inner net.Listener, trusted func(netip.Addr) bool, timeout time.Duration) *Listener {
+	return &Listener{Listener: inner, trusted: trusted, timeout: timeout}
+}
+
+// Accept waits for the next connection. Its header is read on first use, so a slow
+// peer does not hold up other connections.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, trusted: l.trusted, timeout: l.timeout}, nil
+}
+
+// Conn is a connection whose remote address comes from its PROXY protocol header
+type Conn struct {
+	net.Conn
+	trusted func(netip.Addr) bool
+	timeout time.Duration
+
+	once   sync.Once
+	reader io.Reader
+	remote net.Addr
+	err    error
+}
+
+// Read reads data after the header
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the header, or the peer's address when
+// the peer is not trusted or the header carries no address
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	return c.remote
+}
+
+// readHeader reads the header of a trusted peer
+func (c *Conn) readHeader() {
+	c.reader, c.remote = c.Conn, c.Conn.RemoteAddr()
+
+	peer, err := netip.ParseAddrPort(c.remote.String())
+	if err != nil || !c.trusted(peer.Addr().Unmap()) {
+		return
+	}
+
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		c.err = fmt.Errorf("error setting PROXY protocol header deadline: %w", err)
+		return
+	}
+	reader := bufio.NewReaderSize(c.Conn, 256)
+	source, err := ReadHeader(reader)
+	if err != nil {
+		c.err = fmt.Errorf("error reading PROXY protocol header from %s: %w", c.remote, err)
+		return
+	}
+	if err := c.Conn.SetReadDeadline(time.Time{}); err != nil {
+		c.err = fmt.Errorf("error clearing PROXY protocol header deadline: %w", err)
+		return
+	}
+
+	c.reader = reader
+	if source != nil {
+		c.remote = source
+	}
+}
+
+// ReadHeader reads a v1 or v2 header and returns the source address it carries, or nil
+// for headers without one (v1 UNKNOWN, v2 LOCAL or an unsupported address family)
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	start, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	switch {
+	case bytes.Equal(start, v2Signature):
+		return readV2(r)
+	case bytes.HasPrefix(start, []byte("PROXY ")):
+		return readV1(r)
+	default:
+		return nil, errors.New("missing PROXY protocol header")
+	}
+}
+
+// readV1 reads a text header, e.g. "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n"
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < maxV1Length {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("error reading v1 header: %w", err)
+		}
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			return parseV1(strings.TrimSuffix(string(line), "\r\n"))
+		}
+	}
+	return nil, errors.New("v1 header is too long")
+}
+
+// parseV1 parses a v1 header line without its CRLF
+func parseV1(line string) (net.Addr, error) {
+	fields := strings.Split(line, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid v1 header %q", line)
+	}
+
+	source, err := netip.ParseAddr(fields[2])
+	if err != nil || source.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("invalid v1 source address %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v1 source port %q", fields[4])
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(source, uint16(port))), nil
+}
+
+// readV2 reads a binary header: signature, version and command, address family and
+// protocol, length, then the addresses and optional TLVs, which are skipped
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("error reading v2 header: %w", err)
+	}
+
+	versionCommand, family := header[12], header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("error reading v2 addresses: %w", err)
+	}
+
+	if versionCommand>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", versionCommand>>4)
+	}
+	switch versionCommand & 0x0f {
+	case 0x0: // LOCAL, e.g. health checks of the proxy itself
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported v2 command %d", versionCommand&0x0f)
+	}
+
+	var size int
+	switch family >> 4 {
+	case 0x1: // AF_INET
+		size = 4
+	case 0x2: // AF_INET6
+		size = 16
+	default:
+		return nil, nil
+	}
+	if len(payload) < 2*size+4 {
+		return nil, fmt.Errorf("v2 address block of %d bytes is too short", len(payload))
+	}
+
+	source, _ := netip.AddrFromSlice(payload[:size])
+	port := binary.BigEndian.Uint16(payload[2*size : 2*size+2])
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(source.Unmap(), port)), nil
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// BucketSet keeps one token bucket per key, e.g. per client. Buckets idle long
+// enough to be full again are dropped from the least recently used end, since a new
+// bucket behaves the same. A bounded set also evicts the least recently used bucket
+// to make room for a new key.
+type BucketSet struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	idle      time.Duration
+	maxKeys   int                      // 0 is unbounded
+	buckets   map[string]*list.Element // Values are *bucketEntry
+	recent    *list.List               // Most recently used first
+	evictions uint64
+}
+
+// bucketEntry is the bucket of one key in the recency list
+type bucketEntry struct {
+	key    string
+	bucket *TokenBucket
+}
+
+// NewBucketSet creates a set of buckets allowing rate events per second with bursts of burst
+func NewBucketSet(rate float64, burst int) *BucketSet {
+	return NewBoundedBucketSet(rate, burst, 0)
+}
+
+// NewBoundedBucketSet creates a set of buckets that tracks at most maxKeys keys;
+// 0 does not bound it
+func NewBoundedBucketSet(rate float64, burst, maxKeys int) *BucketSet {
+	return &BucketSet{
+		rate:    rate,
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / rate * float64(time.Second)),
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Get returns the bucket of key, creating a full one if needed
+func (s *BucketSet) Get(key string) *TokenBucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropIdle(time.Now())
+
+	if element, exists := s.buckets[key]; exists {
+		s.recent.MoveToFront(element)
+		return element.Value.(*bucketEntry).bucket
+	}
+
+	// An evicted client starts over with a full bucket, so evict the least recently used
+	if s.maxKeys > 0 && len(s.buckets) >= s.maxKeys {
+		s.remove(s.recent.Back())
+		s.evictions++
+	}
+
+	bucket := NewTokenBucket(s.rate, s.burst)
+	s.buckets[key] = s.recent.PushFront(&bucketEntry{key: key, bucket: bucket})
+	return bucket
+}
+
+// Len returns the number of tracked keys
+func (s *BucketSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.buckets)
+}
+
+// Evictions returns how many buckets were evicted to stay within maxKeys
+func (s *BucketSet) Evictions() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.evictions
+}
+
+// dropIdle drops buckets that refilled completely, starting at the least recently used
+// and stopping at the first one still in use, so each call only looks at the buckets it
+// drops and one more; the caller holds s.mu
+func (s *BucketSet) dropIdle(now time.Time) {
+	cutoff := now.Add(-s.idle)
+	for element := s.recent.Back(); element != nil; element = s.recent.Back() {
+		if !element.Value.(*bucketEntry).bucket.idleSince(cutoff) {
+			return
+		}
+		s.remove(element)
+	}
+}
+
+// remove drops the bucket of a recency list element; the caller holds s.mu
+func (s *BucketSet) remove(element *list.Element) {
+	delete(s.buckets, element.Value.(*bucketEntry).key)
+	s.recent.Remove(element)
+}
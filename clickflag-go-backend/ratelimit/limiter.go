@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Rule allows Rate events per second with bursts of Burst
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+// Limiter limits requests per client and, optionally, per client and sub-key, e.g. the
+// country of a click. Each of its bucket sets tracks at most maxKeys keys.
+type Limiter struct {
+	mu      sync.RWMutex
+	clients *BucketSet
+	subKeys *BucketSet // nil without a per sub-key rule
+}
+
+// NewLimiter creates a limiter; a zero subKey rule limits per client only
+func NewLimiter(client, subKey Rule, maxKeys int) *Limiter {
+	l := &Limiter{}
+	l.Reconfigure(client, subKey, maxKeys)
+	return l
+}
+
+// Reconfigure replaces the rules; counts start over under the new ones
+func (l *Limiter) Reconfigure(client, subKey Rule, maxKeys int) {
+	clients := NewBoundedBucketSet(client.Rate, client.Burst, maxKeys)
+
+	var subKeys *BucketSet
+	if subKey.Rate > 0 && subKey.Burst > 0 {
+		subKeys = NewBoundedBucketSet(subKey.Rate, subKey.Burst, maxKeys)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clients, l.subKeys = clients, subKeys
+}
+
+// Allow takes a token for client and, unless subKey is empty, for client and subKey
+func (l *Limiter) Allow(client, subKey string) Decision {
+	return l.AllowAt(time.Now(), client, subKey)
+}
+
+// AllowAt takes the tokens at the given time. Nothing is taken unless every bucket allows
+// the request; the decision of the more restrictive bucket is returned.
+func (l *Limiter) AllowAt(now time.Time, client, subKey string) Decision {
+	l.mu.RLock()
+	clients, subKeys := l.clients, l.subKeys
+	l.mu.RUnlock()
+
+	clientBucket := clients.Get(client)
+	decision := clientBucket.TakeNAt(now, 1)
+	if !decision.Allowed || subKeys == nil || subKey == "" {
+		return decision
+	}
+
+	subDecision := subKeys.Get(client+"|"+subKey).TakeNAt(now, 1)
+	if !subDecision.Allowed {
+		clientBucket.Refund(1)
+		return subDecision
+	}
+	if subDecision.Remaining < decision.Remaining {
+		return subDecision
+	}
+	return decision
+}
+
+// Len returns the number of tracked clients and client and sub-key pairs
+func (l *Limiter) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	n := l.clients.Len()
+	if l.subKeys != nil {
+		n += l.subKeys.Len()
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket allows bursts of up to burst events and refills at rate events per second
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full token bucket
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow takes one token if available
+func (tb *TokenBucket) Allow() bool {
+	return tb.AllowAt(time.Now())
+}
+
+// AllowAt takes one token if available at the given time
+func (tb *TokenBucket) AllowAt(now time.Time) bool {
+	return tb.AllowNAt(now, 1)
+}
+
+// AllowNAt takes n tokens if all of them are available at the given time
+func (tb *TokenBucket) AllowNAt(now time.Time, n int) bool {
+	return tb.TakeNAt(now, n).Allowed
+}
+
+// Decision is the outcome of taking tokens, with the state rate limit headers report
+type Decision struct {
+	Allowed    bool
+	Limit      int           // Burst of the bucket
+	Remaining  int           // Whole tokens left
+	RetryAfter time.Duration // Wait until the tokens would be available; zero when allowed
+	Reset      time.Duration // Wait until the bucket is full again
+}
+
+// TakeNAt takes n tokens if all of them are available at the given time and reports the outcome
+func (tb *TokenBucket) TakeNAt(now time.Time, n int) Decision {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill(now)
+	decision := Decision{Allowed: tb.tokens >= float64(n), Limit: int(tb.burst)}
+	if decision.Allowed {
+		tb.tokens -= float64(n)
+	} else {
+		decision.RetryAfter = tb.timeToEarn(float64(n) - tb.tokens)
+	}
+
+	decision.Remaining = int(math.Floor(tb.tokens))
+	decision.Reset = tb.timeToEarn(tb.burst - tb.tokens)
+	return decision
+}
+
+// Refund gives back n tokens taken by a request that was not carried out
+func (tb *TokenBucket) Refund(n int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.tokens += float64(n)
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+}
+
+// idleSince reports whether the bucket has not been used since the given time
+func (tb *TokenBucket) idleSince(t time.Time) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	return tb.last.Before(t)
+}
+
+// timeToEarn returns how long the bucket takes to earn the given tokens; the caller holds tb.mu
+func (tb *TokenBucket) timeToEarn(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / tb.rate * float64(time.Second)))
+}
+
+// refill adds the tokens earned since the last call; the caller holds tb.mu
+func (tb *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(tb.last).Seconds(); elapsed > 0 {
+		tb.tokens += elapsed * tb.rate
+		if tb.tokens > tb.burst {
+			tb.tokens = tb.burst
+		}
+		tb.last = now
+	}
+}
@@ -0,0 +1,149 @@
+// Package settings defines the setting formats shared by config and the packages that use them
+package settings
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+)
+
+// Sources of the client IP behind trusted proxies
+const (
+	ClientIPXForwardedFor = "x-forwarded-for"
+	ClientIPXRealIP       = "x-real-ip"
+	ClientIPForwarded     = "forwarded" // RFC 7239
+	ClientIPProxyProtocol = "proxy-protocol"
+)
+
+// ClientIPSources lists the accepted client IP sources
+var ClientIPSources = []string{ClientIPXForwardedFor, ClientIPXRealIP, ClientIPForwarded, ClientIPProxyProtocol}
+
+// ParseTrustedProxy parses an IP address or a CIDR range, e.g. 10.0.0.0/8
+func ParseTrustedProxy(proxy string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(proxy); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q, expected an IP address or CIDR range", proxy)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ParseAPIKeys parses "actor:key,actor:key" into keys by actor
+func ParseAPIKeys(spec string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		actor, key, found := strings.Cut(entry, ":")
+		actor, key = strings.TrimSpace(actor), strings.TrimSpace(key)
+		if !found || actor == "" || key == "" {
+			return nil, fmt.Errorf("invalid API key entry %q (expected actor:key)", entry)
+		}
+		if len(key) < 16 {
+			return nil, fmt.Errorf("API key of %s is shorter than 16 characters", actor)
+		}
+		if _, exists := keys[actor]; exists {
+			return nil, fmt.Errorf("duplicate API key actor %q", actor)
+		}
+		keys[actor] = key
+	}
+	return keys, nil
+}
+
+// Database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
+// JournalDurability controls when journal records are fsynced to disk
+type JournalDurability int
+
+const (
+	// DurabilityNone writes records to the OS page cache without fsync.
+	// Clicks survive a process crash but not a machine crash.
+	DurabilityNone JournalDurability = iota
+	// DurabilityBatch groups records and fsyncs them together every journal.SyncInterval.
+	// Append returns once the record's batch is on disk.
+	DurabilityBatch
+	// DurabilityRequest fsyncs every record before Append returns.
+	DurabilityRequest
+)
+
+// ParseJournalDurability parses a durability level from configuration
+func ParseJournalDurability(value string) (JournalDurability, error) {
+	switch strings.ToLower(value) {
+	case "none":
+		return DurabilityNone, nil
+	case "batch", "per-batch":
+		return DurabilityBatch, nil
+	case "request", "per-request":
+		return DurabilityRequest, nil
+	default:
+		return DurabilityNone, fmt.Errorf("unknown journal durability %q (expected none, batch or request)", value)
+	}
+}
+
+// String returns the configuration name of the durability level
+func (d JournalDurability) String() string {
+	switch d {
+	case DurabilityNone:
+		return "none"
+	case DurabilityBatch:
+		return "batch"
+	case DurabilityRequest:
+		return "request"
+	default:
+		return "unknown"
+	}
+}
+
+// LevelCritical is for failures that stop the server
+const LevelCritical = slog.Level(12)
+
+// ParseLogLevel converts a configured level name into a slog level
+func ParseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "critical":
+		return LevelCritical, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q (expected debug, info, warn, error or critical)", name)
+	}
+}
+
+// LogRotation is how often the log file is rotated regardless of its size
+type LogRotation string
+
+const (
+	RotateNever  LogRotation = ""
+	RotateHourly LogRotation = "hourly"
+	RotateDaily  LogRotation = "daily"
+)
+
+// ParseLogRotation converts a configured rotation name into a LogRotation
+func ParseLogRotation(name string) (LogRotation, error) {
+	switch interval := LogRotation(strings.ToLower(name)); interval {
+	case RotateNever, RotateHourly, RotateDaily:
+		return interval, nil
+	case "none", "size":
+		return RotateNever, nil
+	default:
+		return RotateNever, fmt.Errorf("unknown log rotation %q (expected hourly, daily or none)", name)
+	}
+}
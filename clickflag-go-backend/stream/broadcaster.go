@@ -0,0 +1,183 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooManySubscribers is returned when the subscriber cap is reached
+var ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+// ErrClosed is returned when subscribing to a closed broadcaster
+var ErrClosed = errors.New("broadcaster is closed")
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped
+const subscriberBuffer = 16
+
+// Event is one batch of country counts that changed after a cache refresh
+type Event struct {
+	ID     uint64
+	Counts map[string]int // New value of each changed country
+	Deltas map[string]int // How much each changed country moved since the previous event
+}
+
+// Broadcaster fans out count changes to stream subscribers and keeps the most
+// recent events so reconnecting clients can resume from their Last-Event-ID
+type Broadcaster struct {
+	mu             sync.Mutex
+	subscribers    map[*Subscription]struct{}
+	maxSubscribers int
+	history        []Event
+	historySize    int
+	lastID         uint64
+	closed         bool
+}
+
+// Subscription receives events published after it was created
+type Subscription struct {
+	// LastID is the ID of the newest event at subscription time; a snapshot
+	// taken after subscribing is at least this recent
+	LastID uint64
+	// Missed holds the events after the requested Last-Event-ID when Resumed is true
+	Missed  []Event
+	Resumed bool
+
+	events      chan Event
+	broadcaster *Broadcaster
+	closeOnce   sync.Once
+}
+
+// NewBroadcaster creates a broadcaster allowing maxSubscribers concurrent subscribers
+// (0 means unlimited) and keeping historySize events for resuming.
+// Event IDs start at the boot time in milliseconds, so IDs from a previous process
+// are never mistaken for IDs of this one.
+func NewBroadcaster(maxSubscribers, historySize int) *Broadcaster {
+	return &Broadcaster{
+		subscribers:    make(map[*Subscription]struct{}),
+		maxSubscribers: maxSubscribers,
+		historySize:    historySize,
+		lastID:         uint64(time.Now().UnixMilli()),
+	}
+}
+
+// Publish sends the changed counts and their deltas to every subscriber. Subscribers
+// too slow to keep up are dropped; they reconnect and resume from their Last-Event-ID.
+func (b *Broadcaster) Publish(counts, deltas map[string]int) {
+	if b == nil || len(counts) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	event := Event{ID: b.lastID, Counts: counts, Deltas: deltas}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. When lastEventID is not 0 and the events
+// after it are still buffered, they are returned in Missed and Resumed is set.
+func (b *Broadcaster) Subscribe(lastEventID uint64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if b.maxSubscribers > 0 && len(b.subscribers) >= b.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	sub := &Subscription{
+		LastID:      b.lastID,
+		events:      make(chan Event, subscriberBuffer),
+		broadcaster: b,
+	}
+	sub.Missed, sub.Resumed = b.eventsAfter(lastEventID)
+
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// eventsAfter returns the buffered events newer than id, or false when the
+// buffer no longer reaches back that far
+func (b *Broadcaster) eventsAfter(id uint64) ([]Event, bool) {
+	if id == 0 || id > b.lastID {
+		return nil, false
+	}
+	if id == b.lastID {
+		return nil, true
+	}
+	if len(b.history) == 0 || id < b.history[0].ID-1 {
+		return nil, false
+	}
+
+	missed := make([]Event, 0, b.lastID-id)
+	for _, event := range b.history {
+		if event.ID > id {
+			missed = append(missed, event)
+		}
+	}
+	return missed, true
+}
+
+// SubscriberCount returns the number of active subscribers
+func (b *Broadcaster) SubscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Close ends every subscription and rejects new ones, so open streams finish
+// before the server shuts down
+func (b *Broadcaster) Close() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// remove unregisters a subscriber and closes its channel; the caller holds b.mu
+func (b *Broadcaster) remove(sub *Subscription) {
+	delete(b.subscribers, sub)
+	sub.closeOnce.Do(func() { close(sub.events) })
+}
+
+// Events returns the channel of published events; it is closed when the
+// subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes
+func (s *Subscription) Close() {
+	s.broadcaster.mu.Lock()
+	defer s.broadcaster.mu.Unlock()
+
+	s.broadcaster.remove(s)
+}
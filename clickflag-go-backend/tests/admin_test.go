@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/auth"
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/migrations"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	adminTestKey    = "test-key-0123456789"
+	adminTestSecret = "test-secret"
+)
+
+// adminTestApp serves the admin API over store, with API key "alice" and the token secret configured
+func adminTestApp(t *testing.T, store database.Store) (*fiber.App, *cache.Cache) {
+	t.Helper()
+
+	cacheInstance := cache.NewCache()
+	bp := processor.NewBackgroundProcessor(cacheInstance, store, nil, "@every 1h", "")
+	if err := bp.RefreshCache(context.Background()); err != nil {
+		t.Fatalf("RefreshCache failed: %v", err)
+	}
+
+	authenticator := auth.NewAuthenticator(map[string]string{"alice": adminTestKey}, adminTestSecret)
+	adminHandler := handlers.NewAdminHandler(store, cacheInstance, bp)
+
+	app := fiber.New()
+	admin := app.Group("/admin/v1", middleware.AdminAuth(authenticator))
+	admin.Post("/countries/reset", adminHandler.ResetCounts)
+	admin.Put("/countries/:code", adminHandler.SetCountryValue)
+	admin.Post("/countries/:code/adjust", adminHandler.AdjustCountryValue)
+	admin.Post("/countries/:code/freeze", adminHandler.FreezeCountry)
+	admin.Delete("/countries/:code/freeze", adminHandler.UnfreezeCountry)
+	admin.Post("/flush", adminHandler.Flush)
+	admin.Get("/audit", adminHandler.AuditLog)
+	app.Post("/api/v1/countries", handlers.NewCountryHandler(cacheInstance, nil).AddCountry)
+
+	return app, cacheInstance
+}
+
+// adminRequest sends an admin request with the given credential and decodes the response
+func adminRequest(t *testing.T, app *fiber.App, method, path, body, credential string) (int, models.CountryResponse) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request to %s failed: %v", path, err)
+	}
+
+	var response models.CountryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON from %s: %v", path, err)
+	}
+	return resp.StatusCode, response
+}
+
+// TestAuthenticator tests API keys and signed tokens, including tampered and expired tokens
+func TestAuthenticator(t *testing.T) {
+	authenticator := auth.NewAuthenticator(map[string]string{"alice": adminTestKey}, adminTestSecret)
+
+	if actor, err := authenticator.Authenticate(adminTestKey); err != nil || actor != "alice" {
+		t.Errorf("Expected the API key to authenticate alice, got %q, %v", actor, err)
+	}
+	if _, err := authenticator.Authenticate("wrong-key-0123456789"); err == nil {
+		t.Error("An unknown API key should be rejected")
+	}
+
+	token, err := auth.SignToken(adminTestSecret, "deploy-bot", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignToken failed: %v", err)
+	}
+	if actor, err := authenticator.Authenticate(token); err != nil || actor != "deploy-bot" {
+		t.Errorf("Expected the token to authenticate deploy-bot, got %q, %v", actor, err)
+	}
+
+	forged, _ := auth.SignToken("other-secret", "deploy-bot", time.Now().Add(time.Hour))
+	expired, _ := auth.SignToken(adminTestSecret, "deploy-bot", time.Now().Add(-time.Minute))
+	tampered := strings.Replace(token, ".", ".x", 2)
+	for name, credential := range map[string]string{"forged": forged, "expired": expired, "tampered": tampered} {
+		if _, err := authenticator.Authenticate(credential); err == nil {
+			t.Errorf("A %s token should be rejected", name)
+		}
+	}
+}
+
+// TestAdminRequiresAuth tests that admin requests without valid credentials are rejected
+func TestAdminRequiresAuth(t *testing.T) {
+	app, _ := adminTestApp(t, database.NewMemoryStore())
+
+	for _, credential := range []string{"", "wrong-key-0123456789"} {
+		if status, _ := adminRequest(t, app, "PUT", "/admin/v1/countries/TR", `{"value":5}`, credential); status != fiber.StatusUnauthorized {
+			t.Errorf("Expected 401 for credential %q, got %d", credential, status)
+		}
+	}
+}
+
+// TestAdminValueChanges tests set, adjust and reset against both store backends, and their audit entries
+func TestAdminValueChanges(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	stores := map[string]database.Store{
+		"memory": database.NewMemoryStore(),
+		"sqlite": database.NewSQLiteStore(db),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			app, cacheInstance := adminTestApp(t, store)
+			initial, _ := cacheInstance.GetCountryByCode("TR")
+			initialTR := initial.Value
+
+			// Pending clicks are flushed first, so the adjustment applies on top of them
+			cacheInstance.AddPendingUpdateBy("TR", 3)
+
+			if status, _ := adminRequest(t, app, "PUT", "/admin/v1/countries/tr", `{"value":40}`, adminTestKey); status != fiber.StatusOK {
+				t.Fatalf("Expected set to succeed, got %d", status)
+			}
+			cacheInstance.AddPendingUpdateBy("TR", 2)
+			if status, _ := adminRequest(t, app, "POST", "/admin/v1/countries/TR/adjust", `{"delta":-12}`, adminTestKey); status != fiber.StatusOK {
+				t.Fatalf("Expected adjust to succeed, got %d", status)
+			}
+
+			if country, _ := cacheInstance.GetCountryByCode("TR"); country.Value != 30 {
+				t.Errorf("Expected TR to be 30 in the cache, got %d", country.Value)
+			}
+
+			if status, _ := adminRequest(t, app, "POST", "/admin/v1/countries/TR/adjust", `{"delta":-31}`, adminTestKey); status != fiber.StatusUnprocessableEntity {
+				t.Errorf("Expected 422 for a negative count, got %d", status)
+			}
+			if status, _ := adminRequest(t, app, "PUT", "/admin/v1/countries/XX", `{"value":1}`, adminTestKey); status != fiber.StatusBadRequest {
+				t.Errorf("Expected 400 for an unknown country, got %d", status)
+			}
+
+			if status, _ := adminRequest(t, app, "POST", "/admin/v1/countries/reset", "", adminTestKey); status != fiber.StatusBadRequest {
+				t.Errorf("Expected reset without confirm to be rejected, got %d", status)
+			}
+			if status, _ := adminRequest(t, app, "POST", "/admin/v1/countries/reset?confirm=true", "", adminTestKey); status != fiber.StatusOK {
+				t.Fatalf("Expected reset to succeed, got %d", status)
+			}
+			if country, _ := cacheInstance.GetCountryByCode("TR"); country.Value != 0 {
+				t.Errorf("Expected TR to be 0 after reset, got %d", country.Value)
+			}
+
+			entries, err := store.AuditLog(context.Background(), 10)
+			if err != nil {
+				t.Fatalf("AuditLog failed: %v", err)
+			}
+			if len(entries) != 3 {
+				t.Fatalf("Expected 3 audit entries, got %+v", entries)
+			}
+
+			reset, adjust, set := entries[0], entries[1], entries[2]
+			if set.Action != models.AuditSetValue || set.Actor != "alice" || *set.Before != initialTR+3 || *set.After != 40 {
+				t.Errorf("Unexpected set entry %+v", set)
+			}
+			if adjust.Action != models.AuditAdjustValue || *adjust.Before != 42 || *adjust.After != 30 {
+				t.Errorf("Unexpected adjust entry %+v", adjust)
+			}
+			if reset.Action != models.AuditResetCounts || *reset.Before < 30 || *reset.After != 0 || !strings.Contains(reset.Detail, `"TR":30`) {
+				t.Errorf("Unexpected reset entry %+v", reset)
+			}
+		})
+	}
+}
+
+// TestAdminFreeze tests that clicks for a frozen country are rejected until it is unfrozen
+func TestAdminFreeze(t *testing.T) {
+	store := database.NewMemoryStore()
+	app, cacheInstance := adminTestApp(t, store)
+
+	token, err := auth.SignToken(adminTestSecret, "oncall", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignToken failed: %v", err)
+	}
+
+	click := func() int {
+		req := httptest.NewRequest("POST", "/api/v1/countries", strings.NewReader(`{"country_code":"TR"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Click failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if status, _ := adminRequest(t, app, "POST", "/admin/v1/countries/TR/freeze", "", token); status != fiber.StatusOK {
+		t.Fatalf("Expected freeze to succeed, got %d", status)
+	}
+	if !cacheInstance.IsFrozen("TR") {
+		t.Error("TR should be frozen in the cache")
+	}
+	if status := click(); status != fiber.StatusLocked {
+		t.Errorf("Expected 423 for a frozen country, got %d", status)
+	}
+
+	if status, _ := adminRequest(t, app, "DELETE", "/admin/v1/countries/TR/freeze", "", token); status != fiber.StatusOK {
+		t.Fatalf("Expected unfreeze to succeed, got %d", status)
+	}
+	if status := click(); status != fiber.StatusOK {
+		t.Errorf("Expected clicks to be accepted after unfreezing, got %d", status)
+	}
+
+	status, response := adminRequest(t, app, "GET", "/admin/v1/audit?limit=1", "", token)
+	entries, _ := response.Data.([]any)
+	if status != fiber.StatusOK || len(entries) != 1 {
+		t.Fatalf("Expected one audit entry, got %d %+v", status, response)
+	}
+	if entry := entries[0].(map[string]any); entry["action"] != models.AuditUnfreeze || entry["actor"] != "oncall" {
+		t.Errorf("Expected the unfreeze by oncall, got %v", entry)
+	}
+}
+
+// TestAdminFlush tests that a forced flush writes pending clicks and is audited
+func TestAdminFlush(t *testing.T) {
+	store := database.NewMemoryStore()
+	app, cacheInstance := adminTestApp(t, store)
+	cacheInstance.AddPendingUpdateBy("US", 4)
+
+	if status, _ := adminRequest(t, app, "POST", "/admin/v1/flush", "", adminTestKey); status != fiber.StatusOK {
+		t.Fatalf("Expected flush to succeed, got %d", status)
+	}
+	if country, _ := cacheInstance.GetCountryByCode("US"); country.Value != 4 {
+		t.Errorf("Expected US to be 4 after the flush, got %d", country.Value)
+	}
+
+	entries, err := store.AuditLog(context.Background(), 10)
+	if err != nil || len(entries) != 1 || entries[0].Action != models.AuditFlush {
+		t.Errorf("Expected one flush audit entry, got %+v, %v", entries, err)
+	}
+}
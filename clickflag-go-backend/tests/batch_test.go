@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// batchTestApp serves the batch route with small limits: 10 clicks per client
+// and 5 per country in one window
+func batchTestApp(cacheInstance *cache.Cache) *fiber.App {
+	batchHandler := handlers.NewBatchHandler(cacheInstance, nil, handlers.BatchLimits{
+		ClientRate:  1,
+		CountryRate: 0.5,
+		Window:      10 * time.Second,
+	})
+
+	app := fiber.New()
+	app.Post("/api/v1/countries/batch", batchHandler.AddCountriesBatch)
+	return app
+}
+
+// postBatch posts a batch body and decodes the per-item results
+func postBatch(t *testing.T, app *fiber.App, body string) (int, map[string]models.BatchItemResult) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/api/v1/countries/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	results := map[string]models.BatchItemResult{}
+	response := models.CountryResponse{Data: &results}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, results
+}
+
+// TestBatchAccepted tests that a plausible batch is added to pending updates
+func TestBatchAccepted(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	app := batchTestApp(cacheInstance)
+
+	status, results := postBatch(t, app, `{"TR": 5, "US": 2}`)
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if results["TR"].Status != "accepted" || results["US"].Status != "accepted" {
+		t.Errorf("Expected both countries accepted, got %+v", results)
+	}
+
+	pending := cacheInstance.GetPendingUpdates()
+	if pending["TR"] != 5 || pending["US"] != 2 {
+		t.Errorf("Expected TR=5 and US=2 pending, got %v", pending)
+	}
+}
+
+// TestBatchPerItemErrors tests that invalid and implausible items are rejected individually
+func TestBatchPerItemErrors(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	app := batchTestApp(cacheInstance)
+
+	status, results := postBatch(t, app, `{"TR": 6, "US": 3, "XX": 1, "DE": 0}`)
+	if status != fiber.StatusMultiStatus {
+		t.Fatalf("Expected 207, got %d", status)
+	}
+
+	if results["US"].Status != "accepted" {
+		t.Errorf("US should be accepted, got %+v", results["US"])
+	}
+	for _, code := range []string{"TR", "XX", "DE"} {
+		if results[code].Status != "rejected" || results[code].Error == "" {
+			t.Errorf("%s should be rejected with an error, got %+v", code, results[code])
+		}
+	}
+
+	if pending := cacheInstance.GetPendingUpdates(); len(pending) != 1 || pending["US"] != 3 {
+		t.Errorf("Only US should be pending, got %v", pending)
+	}
+}
+
+// TestBatchClientCap tests the per-client cap across countries
+func TestBatchClientCap(t *testing.T) {
+	app := batchTestApp(cache.NewCache())
+
+	if status, _ := postBatch(t, app, `{"TR": 5, "US": 5}`); status != fiber.StatusOK {
+		t.Fatalf("Expected the first window to be accepted, got %d", status)
+	}
+
+	status, results := postBatch(t, app, `{"DE": 1}`)
+	if status != fiber.StatusTooManyRequests || results["DE"].Status != "rejected" {
+		t.Errorf("Expected 429 once the client cap is used up, got %d %+v", status, results)
+	}
+}
+
+// TestBatchInvalidBody tests malformed batch bodies
+func TestBatchInvalidBody(t *testing.T) {
+	app := batchTestApp(cache.NewCache())
+
+	for _, body := range []string{`not json`, `{}`, `{"TR": "five"}`} {
+		req := httptest.NewRequest("POST", "/api/v1/countries/batch", strings.NewReader(body))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+// TestBucketSetKeys tests that buckets are kept per key
+func TestBucketSetKeys(t *testing.T) {
+	set := ratelimit.NewBucketSet(1, 2)
+	now := time.Now()
+
+	if !set.Get("a").AllowNAt(now, 2) {
+		t.Fatal("First key should have a full bucket")
+	}
+	if set.Get("a").AllowNAt(now, 1) {
+		t.Error("First key should be empty")
+	}
+	if !set.Get("b").AllowNAt(now, 2) {
+		t.Error("Second key should have its own bucket")
+	}
+
+	set.Get("a").Refund(1)
+	if !set.Get("a").AllowNAt(now, 1) {
+		t.Error("Refunded token should be available")
+	}
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"clickflag-go-backend/cache"
+	"clickflag-go-backend/constants"
 	"clickflag-go-backend/models"
 	"sync"
 	"testing"
@@ -94,6 +95,48 @@ func TestCacheRefresh(t *testing.T) {
 	}
 }
 
+// TestConcurrentRefreshes tests that concurrent refreshes each get their own version and
+// report deltas that add up to the final value
+func TestConcurrentRefreshes(t *testing.T) {
+	c := cache.NewCache()
+	c.RefreshCountries(countriesWithValue(0))
+	start := c.Version()
+
+	const refreshes = 100
+	deltas := make(chan int, refreshes)
+	var wg sync.WaitGroup
+	for i := 1; i <= refreshes; i++ {
+		wg.Add(1)
+		go func(value int) {
+			defer wg.Done()
+			deltas <- c.RefreshCountries(countriesWithValue(value))["TR"]
+		}(i)
+	}
+	wg.Wait()
+	close(deltas)
+
+	total := 0
+	for delta := range deltas {
+		total += delta
+	}
+	country, _ := c.GetCountryByCode("TR")
+	if total != country.Value {
+		t.Errorf("Expected the deltas to add up to %d, got %d", country.Value, total)
+	}
+	if c.Version() != start+refreshes {
+		t.Errorf("Expected version %d after %d refreshes, got %d", start+refreshes, refreshes, c.Version())
+	}
+}
+
+// countriesWithValue returns every country code with the same value
+func countriesWithValue(value int) []models.Country {
+	countries := make([]models.Country, len(constants.AllCountryCodes))
+	for i, code := range constants.AllCountryCodes {
+		countries[i] = models.Country{ID: i + 1, CountryCode: code, Value: value}
+	}
+	return countries
+}
+
 // TestGetCountryByCode tests individual country retrieval
 func TestGetCountryByCode(t *testing.T) {
 	c := cache.NewCache()
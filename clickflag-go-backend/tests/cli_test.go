@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/auth"
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/cli"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/migrations"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// migratedTestDB opens a temporary SQLite database with every migration applied
+func migratedTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := openTestDB(t)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	return db
+}
+
+// TestValidate tests that the embedded migrations agree with the constants, and that a
+// live database missing a row does not
+func TestValidate(t *testing.T) {
+	report, err := cli.Validate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !report.OK() || len(report.Comparisons) != 4 {
+		var out bytes.Buffer
+		cli.PrintValidation(&out, report)
+		t.Fatalf("Expected the migrations to agree with the constants:\n%s", out.String())
+	}
+
+	db := migratedTestDB(t)
+	if _, err := db.Exec(`DELETE FROM countries WHERE country_code = 'TR'`); err != nil {
+		t.Fatalf("Failed to delete row: %v", err)
+	}
+
+	report, err = cli.Validate(context.Background(), cli.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	live := report.Comparisons[len(report.Comparisons)-1]
+	if report.OK() || len(live.Missing) != 1 || live.Missing[0] != "TR" {
+		t.Errorf("Expected the live database to be missing TR, got %+v", live)
+	}
+}
+
+// TestExportImport tests a CSV and JSON round trip, dry runs and that invalid files change nothing
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	backend := cli.NewStoreBackend(store, "operator")
+
+	if _, err := store.SetCountryValue(ctx, "TR", 5, "setup"); err != nil {
+		t.Fatalf("SetCountryValue failed: %v", err)
+	}
+
+	var exported bytes.Buffer
+	if err := cli.Export(ctx, backend, &exported, cli.FormatCSV); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.HasPrefix(exported.String(), "country_code,value\n") || !strings.Contains(exported.String(), "\nTR,5\n") {
+		t.Errorf("Unexpected CSV export:\n%s", exported.String())
+	}
+
+	// Importing an unchanged export writes nothing
+	result, err := cli.Import(ctx, backend, &exported, cli.FormatCSV, false)
+	if err != nil || len(result.Changed) != 0 {
+		t.Errorf("Expected an unchanged import, got %+v, %v", result, err)
+	}
+
+	changed := `[{"country_code":"tr","value":8},{"country_code":"US","value":3}]`
+	result, err = cli.Import(ctx, backend, strings.NewReader(changed), cli.FormatJSON, true)
+	if err != nil || len(result.Changed) != 2 {
+		t.Fatalf("Expected a dry run with 2 changes, got %+v, %v", result, err)
+	}
+	if counts, _ := backend.Counts(ctx); counts[0].Value != 0 {
+		t.Error("A dry run must not change counts")
+	}
+
+	if _, err := cli.Import(ctx, backend, strings.NewReader(changed), cli.FormatJSON, false); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	var out bytes.Buffer
+	if err := cli.GetCount(ctx, backend, &out, "TR"); err != nil || out.String() != "8\n" {
+		t.Errorf("Expected TR to be 8, got %q, %v", out.String(), err)
+	}
+
+	for name, content := range map[string]string{
+		"duplicate": "TR,1\nTR,2\n",
+		"negative":  "TR,-1\n",
+		"unknown":   "US,1\nXX,1\n",
+	} {
+		if _, err := cli.Import(ctx, backend, strings.NewReader(content), cli.FormatCSV, false); err == nil {
+			t.Errorf("Expected the %s import to fail", name)
+		}
+	}
+
+	entries, err := store.AuditLog(ctx, 10)
+	if err != nil || len(entries) != 3 || entries[0].Actor != "operator" {
+		t.Errorf("Expected only the 2 imported changes to be audited after setup, got %+v, %v", entries, err)
+	}
+}
+
+// TestBackup tests that a backup is a readable copy and is never overwritten
+func TestBackup(t *testing.T) {
+	db := cli.NewDatabase(migratedTestDB(t))
+	path := filepath.Join(t.TempDir(), "backups", "countries.db")
+
+	if err := cli.Backup(context.Background(), db, path); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	backup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer backup.Close()
+
+	var count int
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM countries`).Scan(&count); err != nil || count != 195 {
+		t.Errorf("Expected 195 countries in the backup, got %d, %v", count, err)
+	}
+
+	if err := cli.Backup(context.Background(), db, path); err == nil {
+		t.Error("Backup should refuse to overwrite an existing file")
+	}
+}
+
+// TestAPIBackend tests reading and setting counts through a running admin API
+func TestAPIBackend(t *testing.T) {
+	store := database.NewMemoryStore()
+	cacheInstance := cache.NewCache()
+	bp := processor.NewBackgroundProcessor(cacheInstance, store, nil, "@every 1h", "")
+	if err := bp.RefreshCache(context.Background()); err != nil {
+		t.Fatalf("RefreshCache failed: %v", err)
+	}
+	adminHandler := handlers.NewAdminHandler(store, cacheInstance, bp)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	admin := app.Group("/admin/v1", middleware.AdminAuth(auth.NewAuthenticator(map[string]string{"ops": adminTestKey}, "")))
+	admin.Get("/countries", adminHandler.ListCountries)
+	admin.Put("/countries/:code", adminHandler.SetCountryValue)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	ctx := context.Background()
+	backend := cli.NewAPIBackend("http://"+listener.Addr().String()+"/", adminTestKey)
+
+	var out bytes.Buffer
+	if err := cli.SetCount(ctx, backend, &out, "de", 12); err != nil {
+		t.Fatalf("SetCount failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "DE: 0 -> 12") || !strings.Contains(out.String(), "by ops") {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	if err := cli.GetCount(ctx, backend, &out, "DE"); err != nil || out.String() != "12\n" {
+		t.Errorf("Expected DE to be 12 through the API, got %q, %v", out.String(), err)
+	}
+
+	if _, err := cli.NewAPIBackend("http://"+listener.Addr().String(), "wrong-key-0123456789").Counts(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a 401 error with a wrong key, got %v", err)
+	}
+}
+
+// TestDefaultBackupPath tests that backups go next to the database
+func TestDefaultBackupPath(t *testing.T) {
+	path := cli.DefaultBackupPath("./data/countries.db", time.Date(2024, 6, 1, 10, 20, 30, 0, time.UTC))
+	if path != filepath.Join("data", "backups", "countries-20240601-102030.db") {
+		t.Errorf("Unexpected backup path %s", path)
+	}
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/proxyproto"
+	"clickflag-go-backend/settings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// resolveClientIP serves one request with the given headers through a resolver and
+// returns the client IP it derived; test requests arrive from 0.0.0.0
+func resolveClientIP(t *testing.T, trusted []string, source string, headers map[string][]string) string {
+	t.Helper()
+
+	resolver, err := middleware.NewClientIPResolver(trusted, source)
+	if err != nil {
+		t.Fatalf("NewClientIPResolver failed: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(resolver.Middleware)
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString(middleware.ClientIP(c)) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return string(body)
+}
+
+// TestClientIPResolver tests deriving the client IP through trusted proxies
+func TestClientIPResolver(t *testing.T) {
+	proxies := []string{"0.0.0.0", "10.0.0.0/8"}
+
+	cases := []struct {
+		name    string
+		trusted []string
+		source  string
+		headers map[string][]string
+		want    string
+	}{
+		{"untrusted peer", nil, settings.ClientIPXForwardedFor, map[string][]string{"X-Forwarded-For": {"1.1.1.1"}}, "0.0.0.0"},
+		{"trusted chain", proxies, settings.ClientIPXForwardedFor, map[string][]string{"X-Forwarded-For": {"6.6.6.6, 1.1.1.1", "10.0.0.5"}}, "1.1.1.1"},
+		{"port and IPv6", proxies, settings.ClientIPXForwardedFor, map[string][]string{"X-Forwarded-For": {"[2001:db8::1]:4711"}}, "2001:db8::1"},
+		{"malformed entry", proxies, settings.ClientIPXForwardedFor, map[string][]string{"X-Forwarded-For": {"1.1.1.1, unknown"}}, "0.0.0.0"},
+		{"only proxies", proxies, settings.ClientIPXForwardedFor, map[string][]string{"X-Forwarded-For": {"10.0.0.1, 10.0.0.2"}}, "10.0.0.1"},
+		{"other header ignored", proxies, settings.ClientIPXRealIP, map[string][]string{"X-Forwarded-For": {"1.1.1.1"}}, "0.0.0.0"},
+		{"X-Real-IP", proxies, settings.ClientIPXRealIP, map[string][]string{"X-Real-IP": {"2.2.2.2"}}, "2.2.2.2"},
+		{"Forwarded", proxies, settings.ClientIPForwarded, map[string][]string{"Forwarded": {`for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https`, "for=10.0.0.3;by=10.0.0.4"}}, "2001:db8:cafe::17"},
+		{"Forwarded obfuscated", proxies, settings.ClientIPForwarded, map[string][]string{"Forwarded": {"for=_hidden"}}, "0.0.0.0"},
+		{"PROXY protocol ignores headers", proxies, settings.ClientIPProxyProtocol, map[string][]string{"X-Forwarded-For": {"1.1.1.1"}}, "0.0.0.0"},
+	}
+	for _, tc := range cases {
+		if got := resolveClientIP(t, tc.trusted, tc.source, tc.headers); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	if _, err := middleware.NewClientIPResolver([]string{"10.0.0.0/33"}, settings.ClientIPXForwardedFor); err == nil {
+		t.Error("Expected an invalid CIDR range to be rejected")
+	}
+	if _, err := middleware.NewClientIPResolver(nil, "cf-connecting-ip"); err == nil {
+		t.Error("Expected an unknown source to be rejected")
+	}
+}
+
+// proxyConn sends data over a PROXY protocol listener and returns the accepted connection
+func proxyConn(t *testing.T, trusted bool, data []byte) net.Conn {
+	t.Helper()
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { inner.Close() })
+	listener := proxyproto.NewListener(inner, func(netip.Addr) bool { return trusted }, time.Second)
+
+	client, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Write(data); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readRequestLine reads the first line sent after the PROXY protocol header
+func readRequestLine(t *testing.T, conn net.Conn) string {
+	t.Helper()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read after the header: %v", err)
+	}
+	return line
+}
+
+// TestProxyProtocolV1 tests the text header of PROXY protocol v1
+func TestProxyProtocolV1(t *testing.T) {
+	conn := proxyConn(t, true, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET / HTTP/1.1\r\n"))
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:51234" {
+		t.Errorf("Expected the client address from the header, got %s", got)
+	}
+	if line := readRequestLine(t, conn); line != "GET / HTTP/1.1\r\n" {
+		t.Errorf("Expected the request after the header, got %q", line)
+	}
+
+	conn = proxyConn(t, true, []byte("PROXY UNKNOWN\r\nGET / HTTP/1.1\r\n"))
+	if got := conn.RemoteAddr().String(); !strings.HasPrefix(got, "127.0.0.1:") {
+		t.Errorf("Expected the proxy's address for UNKNOWN, got %s", got)
+	}
+}
+
+// TestProxyProtocolV2 tests the binary header of PROXY protocol v2, including TLVs
+func TestProxyProtocolV2(t *testing.T) {
+	addresses := append(netip.MustParseAddr("2001:db8::7").AsSlice(), netip.MustParseAddr("2001:db8::1").AsSlice()...)
+	addresses = binary.BigEndian.AppendUint16(addresses, 51234)
+	addresses = binary.BigEndian.AppendUint16(addresses, 443)
+	addresses = append(addresses, 0x04, 0x00, 0x01, 0xff) // NOOP TLV
+
+	header := append([]byte("\r\n\r\n\x00\r\nQUIT\n"), 0x21, 0x21)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	header = append(header, addresses...)
+
+	conn := proxyConn(t, true, append(header, "GET / HTTP/1.1\r\n"...))
+	if got := conn.RemoteAddr().String(); got != "[2001:db8::7]:51234" {
+		t.Errorf("Expected the client address from the header, got %s", got)
+	}
+	if line := readRequestLine(t, conn); line != "GET / HTTP/1.1\r\n" {
+		t.Errorf("Expected the request after the header, got %q", line)
+	}
+}
+
+// TestProxyProtocolTrust tests that only trusted peers send headers and must do so
+func TestProxyProtocolTrust(t *testing.T) {
+	conn := proxyConn(t, false, []byte("GET / HTTP/1.1\r\n"))
+	if got := conn.RemoteAddr().String(); !strings.HasPrefix(got, "127.0.0.1:") {
+		t.Errorf("Expected an untrusted peer's own address, got %s", got)
+	}
+	if line := readRequestLine(t, conn); line != "GET / HTTP/1.1\r\n" {
+		t.Errorf("Expected an untrusted peer's data untouched, got %q", line)
+	}
+
+	conn = proxyConn(t, true, []byte("GET / HTTP/1.1\r\n"))
+	if _, err := conn.Read(make([]byte, 16)); err == nil || !strings.Contains(err.Error(), "PROXY protocol") {
+		t.Errorf("Expected a trusted peer without a header to fail, got %v", err)
+	}
+}
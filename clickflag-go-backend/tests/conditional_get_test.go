@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// getCountries requests the countries endpoint with an optional If-None-Match header
+func getCountries(t *testing.T, app *fiber.App, url, ifNoneMatch string) (int, string, models.CountryResponse, map[string]int) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", url, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	counts := map[string]int{}
+	response := models.CountryResponse{Data: &counts}
+	if resp.StatusCode == fiber.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, resp.Header.Get("ETag"), response, counts
+}
+
+// TestCacheSnapshotVersion tests that versions only increase when counts change
+func TestCacheSnapshotVersion(t *testing.T) {
+	c := cache.NewCache()
+	initial := c.Version()
+
+	c.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 1}, {CountryCode: "US", Value: 1}})
+	first := c.Version()
+	if first != initial+1 {
+		t.Errorf("Expected version %d after the first refresh, got %d", initial+1, first)
+	}
+
+	c.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 1}, {CountryCode: "US", Value: 1}})
+	if c.Version() != first {
+		t.Errorf("Refresh without changes should keep version %d, got %d", first, c.Version())
+	}
+
+	c.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 4}, {CountryCode: "US", Value: 1}})
+	changed, current, ok := c.ChangedSince(first)
+	if !ok || current != first+1 || len(changed) != 1 || changed["TR"] != 4 {
+		t.Errorf("Expected only TR=4 since %d, got %v (version %d, ok %v)", first, changed, current, ok)
+	}
+
+	if _, _, ok := c.ChangedSince(initial - 1); ok {
+		t.Error("Unknown versions should not produce a delta")
+	}
+}
+
+// TestGetCountriesConditional tests ETag, If-None-Match and ?since= deltas
+func TestGetCountriesConditional(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 5}, {CountryCode: "US", Value: 2}})
+
+	app := fiber.New()
+	app.Get("/api/v1/countries", handlers.NewCountryHandler(cacheInstance, nil).GetCountries)
+
+	status, etag, response, counts := getCountries(t, app, "/api/v1/countries", "")
+	if status != fiber.StatusOK || etag == "" || len(counts) != 2 {
+		t.Fatalf("Expected full counts with an ETag, got %d %q %v", status, etag, counts)
+	}
+	if etag != `"`+strconv.FormatUint(response.Version, 10)+`"` {
+		t.Errorf("ETag %s should match version %d", etag, response.Version)
+	}
+
+	if status, _, _, _ := getCountries(t, app, "/api/v1/countries", etag); status != fiber.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged version, got %d", status)
+	}
+
+	cacheInstance.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 6}, {CountryCode: "US", Value: 2}})
+
+	if status, _, _, _ := getCountries(t, app, "/api/v1/countries", etag); status != fiber.StatusOK {
+		t.Errorf("Expected 200 after a change, got %d", status)
+	}
+
+	since := strconv.FormatUint(response.Version, 10)
+	status, _, delta, counts := getCountries(t, app, "/api/v1/countries?since="+since, "")
+	if status != fiber.StatusOK || len(counts) != 1 || counts["TR"] != 6 {
+		t.Errorf("Expected only TR=6 since %s, got %d %v", since, status, counts)
+	}
+	if delta.Version != response.Version+1 {
+		t.Errorf("Expected version %d, got %d", response.Version+1, delta.Version)
+	}
+
+	// A version that is no longer buffered gets everything
+	if _, _, _, counts := getCountries(t, app, "/api/v1/countries?since=1", ""); len(counts) != 2 {
+		t.Errorf("Expected all countries for an unknown version, got %v", counts)
+	}
+
+	if status, _, _, _ := getCountries(t, app, "/api/v1/countries?since=abc", ""); status != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid version, got %d", status)
+	}
+}
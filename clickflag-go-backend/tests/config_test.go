@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/config"
+	"clickflag-go-backend/settings"
+)
+
+// writeConfigFile writes a config file into a temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// TestConfigDefaults tests that Load works without a .env or config file
+func TestConfigDefaults(t *testing.T) {
+	cfg, args, err := config.Load([]string{"migrate", "status"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != 8080 || cfg.FlushSchedule != "*/5 * * * * *" || cfg.ClickRate != 20 || cfg.RateLimitMaxKeys != 100000 {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+	if len(cfg.CORSOrigins) != 3 || cfg.CORSOrigins[0] != "http://localhost:3000" {
+		t.Errorf("Expected the development CORS origins, got %v", cfg.CORSOrigins)
+	}
+	if len(args) != 2 || args[0] != "migrate" {
+		t.Errorf("Expected the remaining arguments, got %v", args)
+	}
+}
+
+// TestConfigLayers tests that the environment overrides the file and flags override both
+func TestConfigLayers(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", `
+port: 8081
+click_burst: 50
+request_timeout: 2s
+flush_schedule: "@every 10s"
+cors_origins:
+  - https://example.com
+  - https://www.example.com
+`)
+	t.Setenv("CLICK_BURST", "60")
+	t.Setenv("REQUEST_TIMEOUT", "4s")
+
+	cfg, _, err := config.Load([]string{"-config", path, "-request-timeout", "6s"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != 8081 || cfg.FlushSchedule != "@every 10s" {
+		t.Errorf("Expected the file's port and schedule, got %d and %q", cfg.Port, cfg.FlushSchedule)
+	}
+	if cfg.ClickBurst != 60 {
+		t.Errorf("Expected the environment to override the file, got %d", cfg.ClickBurst)
+	}
+	if cfg.RequestTimeout != 6*time.Second {
+		t.Errorf("Expected the flag to override the environment, got %s", cfg.RequestTimeout)
+	}
+	if strings.Join(cfg.CORSOrigins, ",") != "https://example.com,https://www.example.com" {
+		t.Errorf("Unexpected CORS origins %v", cfg.CORSOrigins)
+	}
+}
+
+// TestConfigTOML tests loading a TOML file selected by CONFIG_FILE
+func TestConfigTOML(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.toml", `
+environment = "production"
+cors_origins = ["https://clickflag.com"]
+db_max_open_conns = 20
+db_conn_max_lifetime = "30m"
+response_compression = false
+`)
+	t.Setenv(config.FileEnv, path)
+
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.DBMaxOpenConns != 20 || cfg.DBMaxIdleConns != 5 || cfg.DBConnMaxLifetime != 30*time.Minute {
+		t.Errorf("Unexpected pool settings %d, %d and %s", cfg.DBMaxOpenConns, cfg.DBMaxIdleConns, cfg.DBConnMaxLifetime)
+	}
+	if cfg.CompressResponses || !cfg.IsProduction() || len(cfg.CORSOrigins) != 1 {
+		t.Errorf("Unexpected production settings: %+v", cfg)
+	}
+}
+
+// TestConfigErrorReport tests that every problem is reported at once
+func TestConfigErrorReport(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", `
+port: 70000
+flush_schedule: "every five seconds"
+db_max_idle_conns: 20
+cors_origins: [localhost:3000]
+trusted_proxies: [10.0.0.0/33]
+`)
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("CLIENT_IP_HEADER", "x-client-ip")
+
+	_, _, err := config.Load([]string{"-config", path, "-admin-port", "70000"})
+	var configErr *config.Error
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected a *config.Error, got %v", err)
+	}
+
+	for _, key := range []string{"port:", "admin_port:", "flush_schedule:", "db_max_idle_conns:", "cors_origins:", "trusted_proxies:", "client_ip_header:", "log_level:"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected a problem with %s in:\n%v", key, err)
+		}
+	}
+}
+
+// TestConfigCORSOriginsRequired tests that staging and production need their CORS origins
+func TestConfigCORSOriginsRequired(t *testing.T) {
+	for _, environment := range []string{"staging", "production"} {
+		t.Setenv("ENVIRONMENT", environment)
+		t.Setenv("CORS_ORIGINS", "")
+		if _, _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "cors_origins: is required in "+environment) {
+			t.Errorf("Expected %s to require cors_origins, got %v", environment, err)
+		}
+
+		t.Setenv("CORS_ORIGINS", "https://clickflag.com")
+		if _, _, err := config.Load(nil); err != nil {
+			t.Errorf("Expected %s with origins to load, got %v", environment, err)
+		}
+	}
+
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("CORS_ORIGINS", "")
+	if _, _, err := config.Load(nil); err != nil {
+		t.Errorf("Expected development to load without origins, got %v", err)
+	}
+}
+
+// TestConfigParseErrors tests that unknown keys and unparsable values name their source
+func TestConfigParseErrors(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", `
+prot: 8080
+read_timeout: 3
+`)
+	t.Setenv("WS_CLICK_RATE", "ten")
+
+	_, _, err := config.Load([]string{"-config", path, "-trace-insecure", "maybe"})
+	if err == nil {
+		t.Fatal("Expected Load to fail")
+	}
+
+	for _, want := range []string{
+		"clickflag.yaml: prot: unknown setting",
+		"clickflag.yaml: read_timeout: invalid duration",
+		"env WS_CLICK_RATE: invalid integer",
+		"flag -trace-insecure: invalid boolean",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+// TestParseAPIKeys tests the ADMIN_API_KEYS format
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := settings.ParseAPIKeys(" alice:" + adminTestKey + ", bob:another-key-0123456789 ,")
+	if err != nil || len(keys) != 2 || keys["alice"] != adminTestKey {
+		t.Errorf("Expected two keys, got %v, %v", keys, err)
+	}
+
+	for _, spec := range []string{"alice", "alice:short", ":" + adminTestKey, "alice:" + adminTestKey + ",alice:" + adminTestKey} {
+		if _, err := settings.ParseAPIKeys(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+// TestParseJournalDurability tests parsing durability levels from configuration
+func TestParseJournalDurability(t *testing.T) {
+	cases := map[string]settings.JournalDurability{
+		"none":        settings.DurabilityNone,
+		"batch":       settings.DurabilityBatch,
+		"per-request": settings.DurabilityRequest,
+	}
+
+	for value, expected := range cases {
+		level, err := settings.ParseJournalDurability(value)
+		if err != nil || level != expected {
+			t.Errorf("ParseJournalDurability(%q) = %v, %v; expected %v", value, level, err, expected)
+		}
+	}
+
+	if _, err := settings.ParseJournalDurability("sometimes"); err == nil {
+		t.Error("Unknown durability should be rejected")
+	}
+}
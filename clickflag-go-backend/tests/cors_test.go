@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/config"
+	"clickflag-go-backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// corsTestApp serves GET and POST /api/v1/countries and a GET history route behind the CORS middleware
+func corsTestApp(policy middleware.CORSPolicy, readOrigins []string) *fiber.App {
+	app := fiber.New()
+	middleware.SetupMiddleware(app, middleware.Options{
+		RequestTimeout:   time.Second,
+		CORS:             policy,
+		ReadCORSOrigins:  readOrigins,
+		PublicReadRoutes: []string{"/api/v1/countries", "/api/v1/countries/:code/history"},
+	})
+
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/api/v1/countries", ok)
+	app.Post("/api/v1/countries", ok)
+	app.Get("/api/v1/countries/:code/history", ok)
+	return app
+}
+
+// corsRequest sends a request with an Origin header; preflightMethod makes it a preflight
+func corsRequest(t *testing.T, app *fiber.App, method, path, origin, preflightMethod string) (int, map[string]string) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Origin", origin)
+	if preflightMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", preflightMethod)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request to %s failed: %v", path, err)
+	}
+
+	headers := make(map[string]string)
+	for _, name := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Allow-Methods", "Access-Control-Max-Age"} {
+		headers[name] = resp.Header.Get(name)
+	}
+	return resp.StatusCode, headers
+}
+
+// TestCORSWildcardSubdomains tests subdomain patterns, credentials and max-age
+func TestCORSWildcardSubdomains(t *testing.T) {
+	app := corsTestApp(middleware.CORSPolicy{
+		Origins:     []string{"https://*.example.com"},
+		Methods:     []string{"GET", "POST"},
+		Credentials: true,
+		MaxAge:      10 * time.Minute,
+	}, nil)
+
+	_, headers := corsRequest(t, app, "POST", "/api/v1/countries", "https://app.example.com", "")
+	if headers["Access-Control-Allow-Origin"] != "https://app.example.com" || headers["Access-Control-Allow-Credentials"] != "true" {
+		t.Errorf("Expected the subdomain to be allowed with credentials, got %v", headers)
+	}
+
+	for _, origin := range []string{"https://example.com", "https://evilexample.com", "http://app.example.com"} {
+		if _, headers := corsRequest(t, app, "GET", "/api/v1/countries", origin, ""); headers["Access-Control-Allow-Origin"] != "" {
+			t.Errorf("Expected %s to be rejected, got %v", origin, headers)
+		}
+	}
+
+	status, headers := corsRequest(t, app, "OPTIONS", "/api/v1/countries", "https://app.example.com", "POST")
+	if status != fiber.StatusNoContent || headers["Access-Control-Allow-Methods"] != "GET,POST" || headers["Access-Control-Max-Age"] != "600" {
+		t.Errorf("Unexpected preflight response %d %v", status, headers)
+	}
+}
+
+// TestCORSPublicReads tests that public read routes allow any origin while writes stay restricted
+func TestCORSPublicReads(t *testing.T) {
+	app := corsTestApp(middleware.CORSPolicy{Origins: []string{"https://clickflag.com"}, Methods: []string{"GET", "POST"}}, []string{"*"})
+
+	if _, headers := corsRequest(t, app, "GET", "/api/v1/countries/", "https://fan-site.org", ""); headers["Access-Control-Allow-Origin"] != "*" {
+		t.Errorf("Expected a public read to allow any origin, got %v", headers)
+	}
+	if _, headers := corsRequest(t, app, "GET", "/api/v1/countries/TR/history", "https://fan-site.org", ""); headers["Access-Control-Allow-Origin"] != "*" {
+		t.Errorf("Expected the history route to allow any origin, got %v", headers)
+	}
+	if _, headers := corsRequest(t, app, "OPTIONS", "/api/v1/countries", "https://fan-site.org", "GET"); headers["Access-Control-Allow-Origin"] != "*" {
+		t.Errorf("Expected a preflight for a read to allow any origin, got %v", headers)
+	}
+
+	if _, headers := corsRequest(t, app, "POST", "/api/v1/countries", "https://fan-site.org", ""); headers["Access-Control-Allow-Origin"] != "" {
+		t.Errorf("Expected a write from another site to be rejected, got %v", headers)
+	}
+	if _, headers := corsRequest(t, app, "OPTIONS", "/api/v1/countries", "https://fan-site.org", "POST"); headers["Access-Control-Allow-Origin"] != "" {
+		t.Errorf("Expected a preflight for a write to be rejected, got %v", headers)
+	}
+	if _, headers := corsRequest(t, app, "POST", "/api/v1/countries", "https://clickflag.com", ""); headers["Access-Control-Allow-Origin"] != "https://clickflag.com" {
+		t.Errorf("Expected writes from cors_origins to be allowed, got %v", headers)
+	}
+}
+
+// TestCORSNoOrigins tests that an empty policy allows no cross-origin requests instead of every origin
+func TestCORSNoOrigins(t *testing.T) {
+	app := corsTestApp(middleware.CORSPolicy{}, nil)
+
+	if _, headers := corsRequest(t, app, "GET", "/api/v1/countries", "https://example.com", ""); headers["Access-Control-Allow-Origin"] != "" {
+		t.Errorf("Expected no CORS headers, got %v", headers)
+	}
+}
+
+// TestCORSConfigValidation tests the validation of CORS settings
+func TestCORSConfigValidation(t *testing.T) {
+	t.Setenv("CORS_ORIGINS", "*,https://example.com,https://app.*.example.com,https://example.com/path")
+	t.Setenv("CORS_METHODS", "GET,FETCH")
+	t.Setenv("CORS_HEADERS", "X-Request-ID,Bad Header")
+	t.Setenv("CORS_CREDENTIALS", "true")
+
+	_, _, err := config.Load(nil)
+	if err == nil {
+		t.Fatal("Expected Load to fail")
+	}
+	for _, want := range []string{
+		"cors_origins: * must be the only origin",
+		`invalid origin "https://app.*.example.com"`,
+		`invalid origin "https://example.com/path"`,
+		`cors_methods: unknown method "FETCH"`,
+		`cors_headers: invalid header name "Bad Header"`,
+		"cors_credentials: cannot be used with the * origin",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+
+	t.Setenv("CORS_ORIGINS", "https://*.example.com,http://localhost:3000")
+	t.Setenv("CORS_METHODS", "GET,POST")
+	t.Setenv("CORS_HEADERS", "X-Request-ID")
+	if _, _, err := config.Load(nil); err != nil {
+		t.Errorf("Expected wildcard subdomains to be valid, got %v", err)
+	}
+}
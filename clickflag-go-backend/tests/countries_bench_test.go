@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/constants"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/models"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// fullCache returns a cache holding every country
+func fullCache(compress bool) *cache.Cache {
+	cacheInstance := cache.NewCache()
+	cacheInstance.SetCompression(compress)
+
+	countries := make([]models.Country, 0, len(constants.AllCountryCodes))
+	for i, code := range constants.AllCountryCodes {
+		countries = append(countries, models.Country{CountryCode: code, Value: i * 1000})
+	}
+	cacheInstance.RefreshCountries(countries)
+
+	return cacheInstance
+}
+
+// TestGetCountriesEncodings tests that each Content-Encoding decodes to the same counts
+func TestGetCountriesEncodings(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/countries", handlers.NewCountryHandler(fullCache(true), nil).GetCountries)
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"":     func(r io.Reader) (io.Reader, error) { return r, nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+
+	for encoding, decode := range decoders {
+		req := httptest.NewRequest("GET", "/api/v1/countries", nil)
+		if encoding != "" {
+			req.Header.Set("Accept-Encoding", encoding)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		if got := resp.Header.Get("Content-Encoding"); got != encoding {
+			t.Errorf("Expected Content-Encoding %q, got %q", encoding, got)
+		}
+
+		body, err := decode(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to decode %q body: %v", encoding, err)
+		}
+
+		counts := map[string]int{}
+		if err := json.NewDecoder(body).Decode(&models.CountryResponse{Data: &counts}); err != nil {
+			t.Fatalf("Failed to parse %q body: %v", encoding, err)
+		}
+		if len(counts) != len(constants.AllCountryCodes) || counts["TR"] == 0 {
+			t.Errorf("Unexpected counts for %q: %d countries", encoding, len(counts))
+		}
+		resp.Body.Close()
+	}
+}
+
+// benchmarkHandler runs a handler on a reused request context
+func benchmarkHandler(b *testing.B, handler fiber.Handler) {
+	app := fiber.New()
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.Header.SetMethod("GET")
+	fctx.Request.SetRequestURI("/api/v1/countries")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := app.AcquireCtx(fctx)
+		if err := handler(c); err != nil {
+			b.Fatal(err)
+		}
+		app.ReleaseCtx(c)
+		fctx.Response.Reset()
+	}
+}
+
+// BenchmarkGetCountriesEncodePerRequest measures the previous approach: build the
+// map and JSON-encode it on every request
+func BenchmarkGetCountriesEncodePerRequest(b *testing.B) {
+	cacheInstance := fullCache(false)
+
+	benchmarkHandler(b, func(c *fiber.Ctx) error {
+		countries := cacheInstance.GetCountries()
+		countryMap := make(map[string]int, len(countries))
+		for _, country := range countries {
+			countryMap[country.CountryCode] = country.Value
+		}
+		return c.JSON(models.CountryResponse{
+			Success: true,
+			Message: "Countries retrieved successfully",
+			Data:    countryMap,
+		})
+	})
+}
+
+// BenchmarkGetCountriesPreEncoded measures serving the body encoded at refresh time
+func BenchmarkGetCountriesPreEncoded(b *testing.B) {
+	benchmarkHandler(b, handlers.NewCountryHandler(fullCache(false), nil).GetCountries)
+}
+
+// BenchmarkCacheRefreshEncoding measures the cost moved into each refresh
+func BenchmarkCacheRefreshEncoding(b *testing.B) {
+	for _, compress := range []bool{false, true} {
+		name := "json"
+		if compress {
+			name = "json+gzip+br"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			cacheInstance := fullCache(compress)
+			countries := make([]models.Country, 0, len(constants.AllCountryCodes))
+			for _, code := range constants.AllCountryCodes {
+				countries = append(countries, models.Country{CountryCode: code})
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				countries[0].Value = i + 1
+				cacheInstance.RefreshCountries(countries)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/health"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestCheckerReport tests the overall status, timeouts and that the last error is kept after recovery
+func TestCheckerReport(t *testing.T) {
+	failing := true
+	checker := health.NewChecker(50 * time.Millisecond)
+	checker.Add("ok", func(ctx context.Context) (map[string]any, error) { return nil, nil })
+	checker.Add("flaky", func(ctx context.Context) (map[string]any, error) {
+		if failing {
+			return nil, errors.New("connection refused")
+		}
+		return nil, nil
+	})
+
+	report := checker.Run(context.Background())
+	if report.Ready() || report.Checks[0].Status != health.StatusUp || report.Checks[1].Error != "connection refused" {
+		t.Fatalf("Expected the flaky check to fail the report, got %+v", report)
+	}
+
+	failing = false
+	report = checker.Run(context.Background())
+	if !report.Ready() {
+		t.Fatalf("Expected the report to recover, got %+v", report)
+	}
+	if flaky := report.Checks[1]; flaky.Error != "" || flaky.LastError != "connection refused" || flaky.LastErrorAt == nil {
+		t.Errorf("Expected the last error to be kept after recovery, got %+v", flaky)
+	}
+
+	checker.Add("hung", func(ctx context.Context) (map[string]any, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	})
+	started := time.Now()
+	report = checker.Run(context.Background())
+	if report.Ready() || time.Since(started) > 500*time.Millisecond {
+		t.Errorf("A hung check should time out and fail the report, got %+v after %s", report, time.Since(started))
+	}
+}
+
+// TestFlushCheck tests that a processor whose flushes fail is reported not ready once the flush is stale
+func TestFlushCheck(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.AddPendingUpdateBy("TR", 1)
+	bp := processor.NewBackgroundProcessor(cacheInstance, failingStore{database.NewMemoryStore()}, nil, "@every 1h", "")
+
+	if _, err := bp.Flush(context.Background()); err == nil {
+		t.Fatal("Flush should fail when the store fails")
+	}
+
+	details, err := health.FlushCheck(bp.FlushStatus, time.Hour)(context.Background())
+	if err != nil {
+		t.Errorf("A recent success should still be ready: %v", err)
+	}
+	if details["last_flush_error"] == nil {
+		t.Error("Expected the failed flush in the details")
+	}
+
+	_, err = health.FlushCheck(bp.FlushStatus, 0)(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "database is locked") {
+		t.Errorf("Expected a stale flush to fail with the store error, got %v", err)
+	}
+}
+
+// TestDiskCheck tests the free space threshold
+func TestDiskCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	details, err := health.DiskCheck(dir, 0)(context.Background())
+	if err != nil {
+		t.Fatalf("DiskCheck failed: %v", err)
+	}
+	if details["total_bytes"].(uint64) == 0 {
+		t.Errorf("Expected the filesystem size, got %v", details)
+	}
+
+	if _, err := health.DiskCheck(dir, math.MaxUint64)(context.Background()); err == nil {
+		t.Error("Expected DiskCheck to fail below the free space threshold")
+	}
+}
+
+// TestHealthEndpoints tests /livez, /readyz and verbose /health
+func TestHealthEndpoints(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", health.PingCheck(database.NewMemoryStore()))
+	checker.Add("cache", health.CacheCheck(cacheInstance))
+
+	healthHandler := handlers.NewHealthHandler(cacheInstance, checker)
+	app := fiber.New()
+	app.Get("/livez", healthHandler.Livez)
+	app.Get("/readyz", healthHandler.Readyz)
+	app.Get("/health", healthHandler.HealthCheck)
+
+	get := func(path string) (int, map[string]any) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatalf("Request to %s failed: %v", path, err)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Invalid JSON from %s: %v", path, err)
+		}
+		return resp.StatusCode, body
+	}
+
+	if status, _ := get("/livez"); status != fiber.StatusOK {
+		t.Errorf("Expected /livez to be 200, got %d", status)
+	}
+
+	// An empty cache is not ready
+	if status, body := get("/readyz"); status != fiber.StatusServiceUnavailable || body["status"] != health.StatusDown {
+		t.Errorf("Expected /readyz to be 503 with an empty cache, got %d %v", status, body)
+	}
+
+	countries, err := database.NewMemoryStore().LoadCountries(context.Background())
+	if err != nil {
+		t.Fatalf("LoadCountries failed: %v", err)
+	}
+	cacheInstance.RefreshCountries(countries)
+	if status, _ := get("/readyz"); status != fiber.StatusOK {
+		t.Errorf("Expected /readyz to be 200 with a loaded cache, got %d", status)
+	}
+
+	if _, body := get("/health"); body["checks"] != nil {
+		t.Error("Checks should only be listed with verbose=1")
+	}
+	status, body := get("/health?verbose=1")
+	checks, _ := body["checks"].([]any)
+	if status != fiber.StatusOK || body["status"] != "healthy" || len(checks) != 2 {
+		t.Errorf("Expected a healthy verbose report with 2 checks, got %d %v", status, body)
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// historyTestApp serves the history routes on top of a memory store with a few clicks
+func historyTestApp(t *testing.T, now time.Time) *fiber.App {
+	t.Helper()
+
+	store := database.NewMemoryStore()
+	ctx := context.Background()
+	if err := store.ApplyIncrements(ctx, map[string]int32{"TR": 3, "US": 1}, now.Add(-2*time.Minute)); err != nil {
+		t.Fatalf("ApplyIncrements failed: %v", err)
+	}
+	if err := store.ApplyIncrements(ctx, map[string]int32{"TR": 2}, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("ApplyIncrements failed: %v", err)
+	}
+
+	historyHandler := handlers.NewHistoryHandler(store)
+	app := fiber.New()
+	app.Get("/api/v1/countries/history", historyHandler.GetHistory)
+	app.Get("/api/v1/countries/:code/history", historyHandler.GetCountryHistory)
+	return app
+}
+
+// getHistory performs a GET request and decodes the response envelope
+func getHistory(t *testing.T, app *fiber.App, url string, data any) (int, models.CountryResponse) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response := models.CountryResponse{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, response
+}
+
+// TestCountryHistoryEndpoint tests the single-country time series
+func TestCountryHistoryEndpoint(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	app := historyTestApp(t, now)
+
+	from := now.Add(-5 * time.Minute).Format(time.RFC3339)
+	to := now.Format(time.RFC3339)
+
+	var history models.CountryHistory
+	status, _ := getHistory(t, app, "/api/v1/countries/TR/history?interval=minute&from="+from+"&to="+to, &history)
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+
+	if history.Interval != "minute" || len(history.Points) != 5 {
+		t.Fatalf("Expected 5 minute buckets, got %s with %d", history.Interval, len(history.Points))
+	}
+	if history.Total != 5 || history.Points[3].Clicks != 3 || history.Points[4].Clicks != 2 {
+		t.Errorf("Unexpected series: %+v", history.Points)
+	}
+}
+
+// TestCompareHistoryEndpoint tests the multi-country variant
+func TestCompareHistoryEndpoint(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	app := historyTestApp(t, now)
+
+	var series []models.CountryHistory
+	status, _ := getHistory(t, app, "/api/v1/countries/history?codes=TR,US&interval=minute", &series)
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+
+	if len(series) != 2 || series[0].CountryCode != "TR" || series[1].CountryCode != "US" {
+		t.Fatalf("Expected TR and US series, got %+v", series)
+	}
+	if series[0].Total != 5 || series[1].Total != 1 {
+		t.Errorf("Expected totals 5 and 1, got %d and %d", series[0].Total, series[1].Total)
+	}
+}
+
+// TestHistoryEndpointValidation tests that bad codes and ranges are rejected
+func TestHistoryEndpointValidation(t *testing.T) {
+	app := historyTestApp(t, time.Now().UTC())
+
+	urls := []string{
+		"/api/v1/countries/XX/history",
+		"/api/v1/countries/TR/history?interval=week",
+		"/api/v1/countries/TR/history?from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z",
+		"/api/v1/countries/TR/history?interval=minute&from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z",
+		"/api/v1/countries/TR/history?from=yesterday",
+		"/api/v1/countries/history",
+		"/api/v1/countries/history?codes=TR,XX",
+	}
+
+	for _, url := range urls {
+		status, response := getHistory(t, app, url, nil)
+		if status != fiber.StatusBadRequest || response.Success {
+			t.Errorf("Expected 400 for %s, got %d", url, status)
+		}
+		if response.Message == "" || response.Message[:1] != strings.ToUpper(response.Message[:1]) {
+			t.Errorf("Expected a capitalized message for %s, got %q", url, response.Message)
+		}
+	}
+
+	if _, response := getHistory(t, app, "/api/v1/countries/TR/history?to=tomorrow", nil); response.Message != "Invalid to: expected RFC 3339 or unix seconds" {
+		t.Errorf("Unexpected message %q", response.Message)
+	}
+}
+
+// TestHistoryEndpointPicksInterval tests that the interval defaults to the finest one fitting the range
+func TestHistoryEndpointPicksInterval(t *testing.T) {
+	app := historyTestApp(t, time.Now().UTC())
+
+	var history models.CountryHistory
+	status, _ := getHistory(t, app, "/api/v1/countries/TR/history?from=2026-01-01T00:00:00Z&to=2026-03-01T00:00:00Z", &history)
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if history.Interval != "hour" {
+		t.Errorf("Expected hour interval for a two month range, got %s", history.Interval)
+	}
+}
@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/migrations"
+	"clickflag-go-backend/processor"
+)
+
+// testStoreHistory runs the shared history contract against a Store implementation
+func testStoreHistory(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	// Two batches in the previous hour and one in the current hour
+	batches := []struct {
+		at         time.Time
+		increments map[string]int32
+	}{
+		{now.Add(-50 * time.Minute), map[string]int32{"TR": 2, "US": 1}},
+		{now.Add(-50*time.Minute + 10*time.Second), map[string]int32{"TR": 3}},
+		{now.Add(-5 * time.Minute), map[string]int32{"TR": 4}},
+	}
+	for _, batch := range batches {
+		if err := store.ApplyIncrements(ctx, batch.increments, batch.at); err != nil {
+			t.Fatalf("ApplyIncrements failed: %v", err)
+		}
+	}
+
+	minutes, err := store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR"},
+		From:         now.Add(-time.Hour),
+		To:           now,
+		Interval:     database.IntervalMinute,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(minutes) != 2 || minutes[0].Clicks != 5 || minutes[1].Clicks != 4 {
+		t.Fatalf("Expected minute buckets [5 4] for TR, got %+v", minutes)
+	}
+	if !minutes[0].Bucket.Equal(now.Add(-50 * time.Minute)) {
+		t.Errorf("Expected first bucket at %s, got %s", now.Add(-50*time.Minute), minutes[0].Bucket)
+	}
+
+	if err := store.RollupHistory(ctx, now); err != nil {
+		t.Fatalf("RollupHistory failed: %v", err)
+	}
+
+	hours, err := store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR", "US"},
+		From:         now.Add(-24 * time.Hour),
+		To:           now.Add(time.Hour),
+		Interval:     database.IntervalHour,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(hours) != 3 {
+		t.Fatalf("Expected 3 hourly buckets, got %+v", hours)
+	}
+	if hours[0].CountryCode != "TR" || hours[0].Clicks != 5 || hours[1].Clicks != 4 {
+		t.Errorf("Expected hourly TR buckets [5 4], got %+v", hours[:2])
+	}
+	if hours[2].CountryCode != "US" || hours[2].Clicks != 1 {
+		t.Errorf("Expected one hourly US click, got %+v", hours[2])
+	}
+
+	// Rolling up twice must not double count
+	if err := store.RollupHistory(ctx, now); err != nil {
+		t.Fatalf("Second RollupHistory failed: %v", err)
+	}
+
+	days, err := store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR"},
+		From:         now.Add(-24 * time.Hour),
+		To:           now.Add(time.Hour),
+		Interval:     database.IntervalDay,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(days) != 1 || days[0].Clicks != 9 {
+		t.Errorf("Expected one daily TR bucket with 9 clicks, got %+v", days)
+	}
+
+	// Pruning drops minute buckets older than the retention but keeps rollups
+	policy := database.RetentionPolicy{Minute: 10 * time.Minute}
+	if err := store.PruneHistory(ctx, policy, now); err != nil {
+		t.Fatalf("PruneHistory failed: %v", err)
+	}
+
+	minutes, err = store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR"},
+		From:         now.Add(-time.Hour),
+		To:           now,
+		Interval:     database.IntervalMinute,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(minutes) != 1 || minutes[0].Clicks != 4 {
+		t.Errorf("Expected only the recent minute bucket after pruning, got %+v", minutes)
+	}
+
+	days, _ = store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR"},
+		From:         now.Add(-24 * time.Hour),
+		To:           now.Add(time.Hour),
+		Interval:     database.IntervalDay,
+	})
+	if len(days) != 1 {
+		t.Errorf("Daily history should survive pruning minutes, got %+v", days)
+	}
+}
+
+// TestMemoryStoreHistory tests click history in the in-memory store
+func TestMemoryStoreHistory(t *testing.T) {
+	testStoreHistory(t, database.NewMemoryStore())
+}
+
+// TestSQLiteStoreHistory tests click history in the SQLite store
+func TestSQLiteStoreHistory(t *testing.T) {
+	db := openTestDB(t)
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	testStoreHistory(t, database.NewSQLiteStore(db))
+}
+
+// testStoreRollupBackfill tests that a rollup after a gap of several hours and days builds
+// every bucket that was missed, before pruning removes the minute buckets they come from
+func testStoreRollupBackfill(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	start := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	if err := store.ApplyIncrements(ctx, map[string]int32{"TR": 2}, start.Add(-20*time.Minute)); err != nil {
+		t.Fatalf("ApplyIncrements failed: %v", err)
+	}
+	if err := store.RollupHistory(ctx, start); err != nil {
+		t.Fatalf("RollupHistory failed: %v", err)
+	}
+
+	// Clicks flushed after the last rollup, then no rollup for three days
+	for at, clicks := range map[time.Time]int32{start.Add(10 * time.Minute): 3, start.Add(50 * time.Minute): 4} {
+		if err := store.ApplyIncrements(ctx, map[string]int32{"TR": clicks}, at); err != nil {
+			t.Fatalf("ApplyIncrements failed: %v", err)
+		}
+	}
+	now := start.Add(72 * time.Hour)
+	if err := store.RollupHistory(ctx, now); err != nil {
+		t.Fatalf("RollupHistory failed: %v", err)
+	}
+
+	hours, err := store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR"},
+		From:         start.Add(-time.Hour),
+		To:           now,
+		Interval:     database.IntervalHour,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(hours) != 2 || hours[0].Clicks != 5 || hours[1].Clicks != 4 {
+		t.Errorf("Expected the missed hourly buckets [5 4], got %+v", hours)
+	}
+
+	policy := database.RetentionPolicy{Minute: 2 * time.Hour, Hour: 48 * time.Hour}
+	if err := store.PruneHistory(ctx, policy, now); err != nil {
+		t.Fatalf("PruneHistory failed: %v", err)
+	}
+
+	days, err := store.History(ctx, database.HistoryQuery{
+		CountryCodes: []string{"TR"},
+		From:         start.Add(-24 * time.Hour),
+		To:           now,
+		Interval:     database.IntervalDay,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(days) != 1 || days[0].Clicks != 9 {
+		t.Errorf("Expected the missed daily bucket to hold 9 clicks, got %+v", days)
+	}
+}
+
+// TestMemoryStoreRollupBackfill tests rollup backfill in the in-memory store
+func TestMemoryStoreRollupBackfill(t *testing.T) {
+	testStoreRollupBackfill(t, database.NewMemoryStore())
+}
+
+// TestSQLiteStoreRollupBackfill tests rollup backfill in the SQLite store
+func TestSQLiteStoreRollupBackfill(t *testing.T) {
+	db := openTestDB(t)
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	testStoreRollupBackfill(t, database.NewSQLiteStore(db))
+}
+
+// TestHistoryMaintainerRun tests that one maintainer run rolls up minute history
+func TestHistoryMaintainerRun(t *testing.T) {
+	store := database.NewMemoryStore()
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	if err := store.ApplyIncrements(context.Background(), map[string]int32{"DE": 6}, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("ApplyIncrements failed: %v", err)
+	}
+
+	maintainer := processor.NewHistoryMaintainer(store, database.RetentionPolicy{Minute: time.Minute}, "@every 1h")
+	if err := maintainer.Run(context.Background(), now); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	hours, err := store.History(context.Background(), database.HistoryQuery{
+		CountryCodes: []string{"DE"},
+		From:         now.Add(-time.Hour),
+		To:           now.Add(time.Hour),
+		Interval:     database.IntervalHour,
+	})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(hours) != 1 || hours[0].Clicks != 6 {
+		t.Errorf("Expected one hourly DE bucket with 6 clicks, got %+v", hours)
+	}
+
+	// The minute retention is raised to the rollup window, so the bucket is kept
+	minutes, _ := store.History(context.Background(), database.HistoryQuery{
+		CountryCodes: []string{"DE"},
+		From:         now.Add(-time.Hour),
+		To:           now,
+		Interval:     database.IntervalMinute,
+	})
+	if len(minutes) != 1 {
+		t.Errorf("Expected the minute bucket to be kept, got %+v", minutes)
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/journal"
+)
+
+// replayInto opens the journal at path and replays it into a fresh cache
+func replayInto(t *testing.T, path string) map[string]int32 {
+	t.Helper()
+
+	j, err := journal.Open(path, journal.DurabilityNone)
+	if err != nil {
+		t.Fatalf("Failed to reopen journal: %v", err)
+	}
+	defer j.Close()
+
+	cacheInstance := cache.NewCache()
+	if _, err := j.Replay(cacheInstance.AddPendingUpdateBy); err != nil {
+		t.Fatalf("Failed to replay journal: %v", err)
+	}
+
+	return cacheInstance.GetPendingUpdates()
+}
+
+// TestJournalReplayAfterCrash tests that uncommitted clicks are replayed for every durability level
+func TestJournalReplayAfterCrash(t *testing.T) {
+	levels := []journal.Durability{journal.DurabilityNone, journal.DurabilityBatch, journal.DurabilityRequest}
+
+	for _, level := range levels {
+		t.Run(level.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "clicks.journal")
+
+			j, err := journal.Open(path, level)
+			if err != nil {
+				t.Fatalf("Failed to open journal: %v", err)
+			}
+
+			cacheInstance := cache.NewCache()
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := j.Append("TR", 1, func() { cacheInstance.AddPendingUpdate("TR") }); err != nil {
+						t.Errorf("Append failed: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// Simulate a crash: close without committing anything
+			j.Close()
+
+			pending := replayInto(t, path)
+			if pending["TR"] != 50 {
+				t.Errorf("Expected 50 replayed clicks for TR, got %d", pending["TR"])
+			}
+		})
+	}
+}
+
+// TestJournalCheckpointCommit tests that committed clicks are not replayed while later ones are
+func TestJournalCheckpointCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clicks.journal")
+
+	j, err := journal.Open(path, journal.DurabilityRequest)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+
+	cacheInstance := cache.NewCache()
+	for i := 0; i < 3; i++ {
+		j.Append("US", 1, func() { cacheInstance.AddPendingUpdate("US") })
+	}
+
+	var drained map[string]int32
+	checkpoint, err := j.Checkpoint(func() { drained = cacheInstance.GetPendingUpdates() })
+	if err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+
+	if drained["US"] != 3 {
+		t.Errorf("Expected 3 drained clicks for US, got %d", drained["US"])
+	}
+
+	// Clicks after the checkpoint belong to the next flush
+	j.Append("DE", 2, func() { cacheInstance.AddPendingUpdateBy("DE", 2) })
+
+	if err := j.Commit(checkpoint); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	j.Close()
+
+	pending := replayInto(t, path)
+	if pending["US"] != 0 {
+		t.Errorf("Committed clicks for US should not be replayed, got %d", pending["US"])
+	}
+	if pending["DE"] != 2 {
+		t.Errorf("Expected 2 replayed clicks for DE, got %d", pending["DE"])
+	}
+}
+
+// TestJournalTornRecord tests that a partially written record is skipped on replay
+func TestJournalTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clicks.journal")
+
+	if err := os.WriteFile(path+".000001", []byte("TR 4\nUS 1\nFR"), 0644); err != nil {
+		t.Fatalf("Failed to write segment: %v", err)
+	}
+
+	pending := replayInto(t, path)
+	if pending["TR"] != 4 || pending["US"] != 1 {
+		t.Errorf("Expected TR=4 and US=1, got %v", pending)
+	}
+	if pending["FR"] != 0 {
+		t.Errorf("Torn record for FR should be skipped, got %d", pending["FR"])
+	}
+}
+
+// TestNilJournal tests that a nil journal behaves as disabled
+func TestNilJournal(t *testing.T) {
+	var j *journal.Journal
+	cacheInstance := cache.NewCache()
+
+	if err := j.Append("TR", 1, func() { cacheInstance.AddPendingUpdate("TR") }); err != nil {
+		t.Fatalf("Append on nil journal failed: %v", err)
+	}
+
+	if !cacheInstance.HasPendingUpdates() {
+		t.Error("Append on nil journal should still apply the click")
+	}
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/logging"
+)
+
+// readLogDir returns the content of every log file in dir by name, gunzipping rotated files
+func readLogDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+
+		if strings.HasSuffix(entry.Name(), ".gz") {
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s is not gzip: %v", entry.Name(), err)
+			}
+			if data, err = io.ReadAll(gz); err != nil {
+				t.Fatalf("Failed to gunzip %s: %v", entry.Name(), err)
+			}
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
+
+// TestFileWriterSizeRotation tests size rotation with gzip compression of rotated files
+func TestFileWriterSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logging.NewFileWriter(logging.FileWriterOptions{Dir: dir, Filename: "app.log", MaxSize: 100, Compress: true})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+
+	line := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files := readLogDir(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("Expected the active file and 2 rotated files, got %v", len(files))
+	}
+	for name, content := range files {
+		if name != "app.log" && !strings.HasSuffix(name, ".gz") {
+			t.Errorf("Rotated file %s should be compressed", name)
+		}
+		if content != line {
+			t.Errorf("Expected one line in %s, got %q", name, content)
+		}
+	}
+}
+
+// TestFileWriterIntervalRotation tests that the file is rotated when the day changes
+func TestFileWriterIntervalRotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+	w, err := logging.NewFileWriter(logging.FileWriterOptions{
+		Dir:      dir,
+		Filename: "app.log",
+		Interval: logging.RotateDaily,
+		Now:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+
+	w.Write([]byte("day one\n"))
+	now = now.Add(30 * time.Second)
+	w.Write([]byte("still day one\n"))
+	now = now.Add(time.Minute)
+	w.Write([]byte("day two\n"))
+	w.Close()
+
+	files := readLogDir(t, dir)
+	if files["app.log"] != "day two\n" {
+		t.Errorf("Expected only day two in the active file, got %q", files["app.log"])
+	}
+	if rotated := files["app.log.2024-01-02-00-00-30"]; rotated != "day one\nstill day one\n" {
+		t.Errorf("Expected day one in the rotated file, got %v", files)
+	}
+}
+
+// TestFileWriterRetention tests that only the newest rotated files are kept
+func TestFileWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logging.NewFileWriter(logging.FileWriterOptions{Dir: dir, Filename: "app.log", MaxSize: 10, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		w.Write([]byte(fmt.Sprintf("line %d...\n", i)))
+	}
+	w.Close()
+
+	files := readLogDir(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("Expected the active file and 2 rotated files, got %d", len(files))
+	}
+
+	var contents []string
+	for _, content := range files {
+		contents = append(contents, content)
+	}
+	sort.Strings(contents)
+	if contents[0] != "line 3...\n" || contents[2] != "line 5...\n" {
+		t.Errorf("Expected the newest lines to be kept, got %q", contents)
+	}
+}
+
+// TestFileWriterConcurrentWrites tests that concurrent writes are neither lost nor interleaved
+func TestFileWriterConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logging.NewFileWriter(logging.FileWriterOptions{Dir: dir, Filename: "app.log", MaxSize: 1024, Compress: true})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				w.Write([]byte(fmt.Sprintf("writer %d line %03d\n", g, i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+	w.Close()
+
+	total := 0
+	for name, content := range readLogDir(t, dir) {
+		for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if !strings.HasPrefix(line, "writer ") || len(line) != len("writer 0 line 000") {
+				t.Fatalf("Corrupted line in %s: %q", name, line)
+			}
+			total++
+		}
+	}
+	if total != 800 {
+		t.Errorf("Expected 800 lines across all files, got %d", total)
+	}
+}
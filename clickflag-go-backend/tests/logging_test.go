@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// decodeLogLines parses every JSON log record written to buf
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Log line is not JSON: %q", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestLoggerLevels tests level filtering and the CRITICAL level name
+func TestLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(logging.Options{Level: "warn", Output: &buf})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Info("hidden")
+	logger.Warn("shown", "country_code", "TR")
+	logger.Log(context.Background(), logging.LevelCritical, "fatal")
+
+	records := decodeLogLines(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records above warn, got %d", len(records))
+	}
+	if records[0]["level"] != "WARN" || records[0]["country_code"] != "TR" {
+		t.Errorf("Unexpected warn record: %v", records[0])
+	}
+	if records[1]["level"] != "CRITICAL" {
+		t.Errorf("Expected CRITICAL level name, got %v", records[1]["level"])
+	}
+
+	if _, err := logging.New(logging.Options{Level: "verbose"}); err == nil {
+		t.Error("Unknown level should be rejected")
+	}
+}
+
+// TestLoggerFromContext tests that the request-scoped logger is found in the context
+func TestLoggerFromContext(t *testing.T) {
+	if logging.FromContext(context.Background()) != slog.Default() {
+		t.Error("Expected the default logger without a request-scoped one")
+	}
+
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	ctx := logging.WithLogger(context.Background(), logger)
+	if logging.FromContext(ctx) != logger {
+		t.Error("Expected the request-scoped logger")
+	}
+}
+
+// TestRequestLogger tests the access log record with request ID and client IP
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(logging.Options{Level: "debug", Output: &buf})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	app := fiber.New()
+	app.Use(middleware.RequestID)
+	app.Use(middleware.RequestLogger)
+	app.Get("/test", func(c *fiber.Ctx) error {
+		middleware.Logger(c).Info("handler", "country_code", "US")
+		return c.SendStatus(fiber.StatusTeapot)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-123")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	records := decodeLogLines(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected handler and access records, got %d", len(records))
+	}
+	for _, record := range records {
+		if record["request_id"] != "req-123" || record["ip"] == nil {
+			t.Errorf("Record is missing request attributes: %v", record)
+		}
+	}
+
+	access := records[1]
+	if access["status"] != float64(fiber.StatusTeapot) || access["path"] != "/test" || access["level"] != "WARN" {
+		t.Errorf("Unexpected access record: %v", access)
+	}
+}
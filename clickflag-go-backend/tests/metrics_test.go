@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/metrics"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// scrapeMetrics returns the Prometheus text exposition of the registry
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	return string(body)
+}
+
+// TestClickAndHTTPMetrics tests click counters and route latency labels
+func TestClickAndHTTPMetrics(t *testing.T) {
+	countryHandler := handlers.NewCountryHandler(cache.NewCache(), nil)
+	app := fiber.New()
+	app.Use(middleware.MetricsMiddleware)
+	app.Post("/api/v1/countries", countryHandler.AddCountry)
+
+	before := testutil.ToFloat64(metrics.ClicksTotal.WithLabelValues("NZ", "http"))
+
+	req := httptest.NewRequest("POST", "/api/v1/countries", strings.NewReader(`{"country_code":"NZ"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if _, err := app.Test(httptest.NewRequest("GET", "/does/not/exist", nil)); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if after := testutil.ToFloat64(metrics.ClicksTotal.WithLabelValues("NZ", "http")); after != before+1 {
+		t.Errorf("Expected NZ click counter to increase by 1, got %v -> %v", before, after)
+	}
+
+	text := scrapeMetrics(t)
+	for _, series := range []string{
+		`clickflag_http_request_duration_seconds_count{method="POST",route="/api/v1/countries",status="200"}`,
+		`clickflag_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"}`,
+	} {
+		if !strings.Contains(text, series) {
+			t.Errorf("Expected series %s in metrics output", series)
+		}
+	}
+}
+
+// TestFlushMetrics tests flush histograms and DB error counters
+func TestFlushMetrics(t *testing.T) {
+	flushes := testutil.CollectAndCount(metrics.FlushDuration)
+	if flushes != 1 {
+		t.Fatalf("Expected one flush duration histogram, got %d", flushes)
+	}
+
+	cacheInstance := cache.NewCache()
+	cacheInstance.AddPendingUpdateBy("TR", 3)
+
+	errorsBefore := testutil.ToFloat64(metrics.DBErrors.WithLabelValues("apply_increments"))
+
+	bp := processor.NewBackgroundProcessor(cacheInstance, failingStore{database.NewMemoryStore()}, nil, "@every 1h", "")
+	if _, err := bp.Flush(context.Background()); err == nil {
+		t.Fatal("Flush should fail when the store fails")
+	}
+
+	if errorsAfter := testutil.ToFloat64(metrics.DBErrors.WithLabelValues("apply_increments")); errorsAfter != errorsBefore+1 {
+		t.Errorf("Expected apply_increments errors to increase by 1, got %v -> %v", errorsBefore, errorsAfter)
+	}
+
+	if !strings.Contains(scrapeMetrics(t), "clickflag_flush_batch_clicks_bucket") {
+		t.Error("Expected flush batch histogram in metrics output")
+	}
+}
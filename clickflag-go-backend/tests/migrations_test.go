@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/migrations"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB opens an empty SQLite database in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// TestMigrationsApplyOnce tests that migrations run once and are recorded
+func TestMigrationsApplyOnce(t *testing.T) {
+	db := openTestDB(t)
+
+	migrator, err := database.NewMigrator(db, os.DirFS("../migrations"))
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if applied < 2 {
+		t.Errorf("Expected at least 2 applied migrations, got %d", applied)
+	}
+
+	// Second run must be a no-op
+	applied, err = migrator.Up()
+	if err != nil {
+		t.Fatalf("Second Up failed: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Expected 0 migrations on second run, got %d", applied)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM countries WHERE country_code = 'SS'`).Scan(&count); err != nil {
+		t.Fatalf("Failed to query countries: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected SS to be seeded once, got %d rows", count)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("Migration %03d should be applied", status.Version)
+		}
+	}
+}
+
+// TestEmbeddedMigrationsMatchDirectory tests that the embedded migrations match the files on disk
+func TestEmbeddedMigrationsMatchDirectory(t *testing.T) {
+	embedded, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load embedded migrations: %v", err)
+	}
+
+	onDisk, err := database.LoadMigrations(os.DirFS("../migrations"))
+	if err != nil {
+		t.Fatalf("Failed to load migrations from disk: %v", err)
+	}
+
+	if len(embedded) == 0 || len(embedded) != len(onDisk) {
+		t.Fatalf("Expected %d embedded migrations, got %d", len(onDisk), len(embedded))
+	}
+
+	for i := range embedded {
+		if embedded[i].Checksum != onDisk[i].Checksum {
+			t.Errorf("Embedded migration %03d differs from the file on disk", embedded[i].Version)
+		}
+	}
+
+	// A fresh database can be booted from the embedded files alone
+	migrator, err := database.NewMigrator(openTestDB(t), migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up with embedded migrations failed: %v", err)
+	}
+}
+
+// TestMigrationsDown tests rolling back the latest migration
+func TestMigrationsDown(t *testing.T) {
+	db := openTestDB(t)
+
+	migrator, err := database.NewMigrator(db, os.DirFS("../migrations"))
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	statuses, _ := migrator.Status()
+	reverted, err := migrator.Down(len(statuses))
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if reverted != len(statuses) {
+		t.Errorf("Expected %d rolled back migrations, got %d", len(statuses), reverted)
+	}
+
+	var name string
+	err = db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'countries'`).Scan(&name)
+	if err != sql.ErrNoRows {
+		t.Errorf("countries table should be dropped after rolling back everything, got %v", err)
+	}
+}
+
+// TestMigrationChecksumMismatch tests that a modified applied migration is refused
+func TestMigrationChecksumMismatch(t *testing.T) {
+	db := openTestDB(t)
+
+	original := fstest.MapFS{
+		"001_create_things.sql": {Data: []byte("CREATE TABLE things (id INTEGER);")},
+	}
+	migrator, err := database.NewMigrator(db, original)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	modified := fstest.MapFS{
+		"001_create_things.sql": {Data: []byte("CREATE TABLE things (id INTEGER, name TEXT);")},
+	}
+	migrator, err = database.NewMigrator(db, modified)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	_, err = migrator.Up()
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch error, got %v", err)
+	}
+}
+
+// TestFailedMigrationRollsBack tests that a failing migration leaves no partial changes
+func TestFailedMigrationRollsBack(t *testing.T) {
+	db := openTestDB(t)
+
+	files := fstest.MapFS{
+		"001_broken.sql": {Data: []byte("CREATE TABLE partial (id INTEGER); INSERT INTO missing VALUES (1);")},
+	}
+	migrator, err := database.NewMigrator(db, files)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if _, err := migrator.Up(); err == nil {
+		t.Fatal("Broken migration should fail")
+	}
+
+	var name string
+	err = db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'partial'`).Scan(&name)
+	if err != sql.ErrNoRows {
+		t.Errorf("Failed migration should be rolled back, got %v", err)
+	}
+}
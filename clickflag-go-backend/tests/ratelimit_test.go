@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestTokenBucketDecision tests the remaining tokens and waits reported by a bucket
+func TestTokenBucketDecision(t *testing.T) {
+	bucket := ratelimit.NewTokenBucket(1, 2)
+	now := time.Now()
+
+	bucket.TakeNAt(now, 1)
+	decision := bucket.TakeNAt(now, 1)
+	if !decision.Allowed || decision.Limit != 2 || decision.Remaining != 0 || decision.Reset != 2*time.Second {
+		t.Errorf("Unexpected decision for the last token: %+v", decision)
+	}
+
+	decision = bucket.TakeNAt(now.Add(500*time.Millisecond), 1)
+	if decision.Allowed || decision.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected a refusal with half a second to wait, got %+v", decision)
+	}
+}
+
+// TestBoundedBucketSet tests that the least recently used key is evicted
+func TestBoundedBucketSet(t *testing.T) {
+	set := ratelimit.NewBoundedBucketSet(1, 2, 2)
+
+	first := set.Get("a")
+	set.Get("b")
+	set.Get("a")
+	set.Get("c")
+
+	if set.Len() != 2 || set.Evictions() != 1 {
+		t.Errorf("Expected 2 keys after 1 eviction, got %d and %d", set.Len(), set.Evictions())
+	}
+	if set.Get("a") != first {
+		t.Error("The recently used key should have kept its bucket")
+	}
+	if set.Len() != 2 {
+		t.Errorf("Expected the set to stay bounded, got %d keys", set.Len())
+	}
+}
+
+// TestBucketSetDropsIdleBuckets tests that buckets that refilled are dropped on the next Get
+func TestBucketSetDropsIdleBuckets(t *testing.T) {
+	set := ratelimit.NewBucketSet(1000, 1)
+
+	set.Get("a")
+	set.Get("b")
+	time.Sleep(10 * time.Millisecond)
+	set.Get("c")
+
+	if set.Len() != 1 {
+		t.Errorf("Expected only the new key to be tracked, got %d keys", set.Len())
+	}
+	if set.Evictions() != 0 {
+		t.Errorf("Dropping idle buckets should not count as evictions, got %d", set.Evictions())
+	}
+}
+
+// TestLimiterSubKeys tests that a request needs both buckets and a refusal takes nothing
+func TestLimiterSubKeys(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 3}, ratelimit.Rule{Rate: 1, Burst: 1}, 100)
+	now := time.Now()
+
+	if !limiter.AllowAt(now, "1.1.1.1", "TR").Allowed {
+		t.Fatal("Expected the first click to be allowed")
+	}
+	if decision := limiter.AllowAt(now, "1.1.1.1", "TR"); decision.Allowed || decision.Limit != 1 {
+		t.Errorf("Expected the country bucket to refuse the second click, got %+v", decision)
+	}
+	for _, code := range []string{"US", "DE"} {
+		if !limiter.AllowAt(now, "1.1.1.1", code).Allowed {
+			t.Errorf("Expected %s to be allowed, the refused click must not count", code)
+		}
+	}
+	if decision := limiter.AllowAt(now, "1.1.1.1", "FR"); decision.Allowed || decision.Limit != 3 {
+		t.Errorf("Expected the client bucket to refuse the fourth click, got %+v", decision)
+	}
+	if !limiter.AllowAt(now, "2.2.2.2", "FR").Allowed {
+		t.Error("Expected another client to have its own buckets")
+	}
+}
+
+// TestClickRateLimit tests the rate limit headers and 429 of POST /api/v1/countries
+func TestClickRateLimit(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	countryHandler := handlers.NewCountryHandler(cacheInstance, nil)
+	countryHandler.SetClickLimiter(ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 5}, ratelimit.Rule{Rate: 1, Burst: 2}, 100))
+
+	app := fiber.New()
+	app.Post("/api/v1/countries", countryHandler.AddCountry)
+
+	click := func(code string) *http.Response {
+		req := httptest.NewRequest("POST", "/api/v1/countries", strings.NewReader(`{"country_code":"`+code+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := click("TR")
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("X-RateLimit-Limit") != "2" || resp.Header.Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("Expected 200 reporting the country bucket, got %d with %v", resp.StatusCode, resp.Header)
+	}
+
+	click("TR")
+	resp = click("TR")
+	if resp.StatusCode != fiber.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("Expected 429 with Retry-After, got %d with %v", resp.StatusCode, resp.Header)
+	}
+	if reset, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Reset")); reset < 1 || reset > 2 {
+		t.Errorf("Unexpected X-RateLimit-Reset %q", resp.Header.Get("X-RateLimit-Reset"))
+	}
+
+	if resp := click("US"); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected another country to be allowed, got %d", resp.StatusCode)
+	}
+	if cacheInstance.GetPendingUpdates()["TR"] != 2 {
+		t.Errorf("Expected 2 pending TR clicks, got %v", cacheInstance.GetPendingUpdates())
+	}
+}
+
+// TestFrozenClicksSkipRateLimit tests that clicks on a frozen country spend no tokens
+func TestFrozenClicksSkipRateLimit(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.SetFrozenCountries([]string{"DE"})
+	countryHandler := handlers.NewCountryHandler(cacheInstance, nil)
+	countryHandler.SetClickLimiter(ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 3}, ratelimit.Rule{Rate: 1, Burst: 3}, 100))
+
+	app := fiber.New()
+	app.Post("/api/v1/countries", countryHandler.AddCountry)
+
+	click := func(code string) *http.Response {
+		req := httptest.NewRequest("POST", "/api/v1/countries", strings.NewReader(`{"country_code":"`+code+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	for i := 0; i < 5; i++ {
+		if resp := click("DE"); resp.StatusCode != fiber.StatusLocked || resp.Header.Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("Expected 423 without rate limit headers, got %d with %v", resp.StatusCode, resp.Header)
+		}
+	}
+
+	if resp := click("US"); resp.StatusCode != fiber.StatusOK || resp.Header.Get("X-RateLimit-Remaining") != "2" {
+		t.Errorf("Expected the frozen clicks to leave the client's tokens, got %d with %v", resp.StatusCode, resp.Header)
+	}
+}
+
+// TestReadRateLimit tests that only GET requests of the limited routes count
+func TestReadRateLimit(t *testing.T) {
+	app := fiber.New()
+	middleware.SetupMiddleware(app, middleware.Options{RequestTimeout: time.Second})
+	app.Get("/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	api := app.Group("/api/v1", middleware.ReadRateLimit(ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 2}, ratelimit.Rule{}, 100)))
+	api.Get("/countries", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	api.Post("/countries", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	status := func(method, path string) int {
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := status("GET", "/api/v1/countries"); got != fiber.StatusOK {
+			t.Fatalf("Expected read %d to be allowed, got %d", i+1, got)
+		}
+	}
+	if got := status("GET", "/api/v1/countries"); got != fiber.StatusTooManyRequests {
+		t.Errorf("Expected the third read to be limited, got %d", got)
+	}
+	if got := status("POST", "/api/v1/countries"); got != fiber.StatusOK {
+		t.Errorf("Expected POST to be left to its handler, got %d", got)
+	}
+	if got := status("GET", "/health"); got != fiber.StatusOK {
+		t.Errorf("Expected /health not to be limited, got %d", got)
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/processor"
+)
+
+// TestRecoveryFileRoundTrip tests that spilled counts are replayed into pending updates
+func TestRecoveryFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recovery", "pending.json")
+
+	if err := processor.SaveRecoveryFile(path, map[string]int32{"TR": 5, "US": 2}); err != nil {
+		t.Fatalf("Failed to save recovery file: %v", err)
+	}
+
+	// A second spill should be merged with the first one
+	if err := processor.SaveRecoveryFile(path, map[string]int32{"TR": 3}); err != nil {
+		t.Fatalf("Failed to save recovery file: %v", err)
+	}
+
+	cacheInstance := cache.NewCache()
+	replayed, err := processor.ReplayRecoveryFile(path, cacheInstance)
+	if err != nil {
+		t.Fatalf("Failed to replay recovery file: %v", err)
+	}
+
+	if replayed != 10 {
+		t.Errorf("Expected 10 replayed clicks, got %d", replayed)
+	}
+
+	pending := cacheInstance.GetPendingUpdates()
+	if pending["TR"] != 8 {
+		t.Errorf("Expected 8 pending updates for TR, got %d", pending["TR"])
+	}
+	if pending["US"] != 2 {
+		t.Errorf("Expected 2 pending updates for US, got %d", pending["US"])
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Error("Recovery file should be kept until a flush writes the replayed counts")
+	}
+}
+
+// TestReplayedRecoveryRemovedAfterFlush tests that the recovery file outlives failed flushes,
+// is not counted twice when spilled again, and is removed once a flush writes its counts
+func TestReplayedRecoveryRemovedAfterFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recovery.json")
+	if err := processor.SaveRecoveryFile(path, map[string]int32{"TR": 5}); err != nil {
+		t.Fatalf("Failed to save recovery file: %v", err)
+	}
+
+	cacheInstance := cache.NewCache()
+	bp := processor.NewBackgroundProcessor(cacheInstance, failingStore{database.NewMemoryStore()}, nil, "@every 1h", path)
+	if replayed, err := bp.ReplayRecovery(); err != nil || replayed != 5 {
+		t.Fatalf("Expected 5 replayed clicks, got %d, %v", replayed, err)
+	}
+	if _, err := bp.Flush(context.Background()); err == nil {
+		t.Fatal("Flush should fail when the store fails")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("A failed flush must keep the recovery file")
+	}
+
+	cacheInstance.AddPendingUpdateBy("TR", 1)
+	if err := bp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown should spill instead of failing: %v", err)
+	}
+	if spilled, err := processor.LoadRecoveryFile(path); err != nil || spilled["TR"] != 6 {
+		t.Fatalf("Expected 6 spilled TR clicks without counting the replay twice, got %v, %v", spilled, err)
+	}
+
+	store := database.NewMemoryStore()
+	before := countsByCode(t, store)["TR"]
+	bp = processor.NewBackgroundProcessor(cache.NewCache(), store, nil, "@every 1h", path)
+	if _, err := bp.ReplayRecovery(); err != nil {
+		t.Fatalf("Failed to replay recovery file: %v", err)
+	}
+	if _, err := bp.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if counts := countsByCode(t, store); counts["TR"] != before+6 {
+		t.Errorf("Expected TR to grow by 6, got %d -> %d", before, counts["TR"])
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Recovery file should be removed once a flush wrote its counts")
+	}
+}
+
+// TestReplayMissingRecoveryFile tests that a missing recovery file is not an error
+func TestReplayMissingRecoveryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+
+	replayed, err := processor.ReplayRecoveryFile(path, cache.NewCache())
+	if err != nil {
+		t.Fatalf("Missing recovery file should not be an error: %v", err)
+	}
+
+	if replayed != 0 {
+		t.Errorf("Expected 0 replayed clicks, got %d", replayed)
+	}
+}
@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/auth"
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/config"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/logging"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestReloader tests that runtime settings are swapped in, others are reported, and an
+// invalid file changes nothing
+func TestReloader(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", "click_burst: 40\nport: 8080\n")
+	args := []string{"-config", path}
+
+	cfg, _, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	reloader := config.NewReloader(cfg, args)
+
+	var hookOld, hookUpdated *config.Config
+	reloader.OnReload(func(old, updated *config.Config) error {
+		hookOld, hookUpdated = old, updated
+		return nil
+	})
+
+	if err := os.WriteFile(path, []byte("click_burst: 50\nport: 8081\nadmin_token_secret: secret-0123456789-0123456789-0123\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	result, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if len(result.Applied) != 1 || result.Applied[0] != (config.Change{Key: "click_burst", Old: "40", New: "50"}) {
+		t.Errorf("Expected click_burst to be applied, got %+v", result.Applied)
+	}
+	if len(result.RestartRequired) != 2 || result.RestartRequired[0].Key != "port" || result.RestartRequired[1].New != "(hidden)" {
+		t.Errorf("Expected port and a hidden secret to need a restart, got %+v", result.RestartRequired)
+	}
+	if current := reloader.Current(); current.ClickBurst != 50 || current.Port != 8080 || current.AdminTokenSecret != "" {
+		t.Errorf("Expected only the runtime setting to change, got %+v", current)
+	}
+	if hookOld != cfg || hookUpdated != reloader.Current() {
+		t.Error("Expected the hook to receive the old and the updated configuration")
+	}
+
+	if err := os.WriteFile(path, []byte("click_burst: 0\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	if _, err := reloader.Reload(); err == nil || !strings.Contains(err.Error(), "click_burst") {
+		t.Errorf("Expected the invalid file to be rejected, got %v", err)
+	}
+	if reloader.Current().ClickBurst != 50 {
+		t.Error("An invalid configuration must not change the running one")
+	}
+}
+
+// TestReloaderRollback tests that a failing hook keeps the running configuration and
+// that the hooks which ran restore it
+func TestReloaderRollback(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", "click_burst: 40\n")
+	args := []string{"-config", path}
+
+	cfg, _, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	reloader := config.NewReloader(cfg, args)
+
+	applied := cfg.ClickBurst
+	reloader.OnReload(func(old, updated *config.Config) error {
+		applied = updated.ClickBurst
+		return nil
+	})
+	reloader.OnReload(func(old, updated *config.Config) error {
+		if updated.ClickBurst == 50 {
+			return errors.New("schedule rejected")
+		}
+		return nil
+	})
+
+	if err := os.WriteFile(path, []byte("click_burst: 50\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	result, err := reloader.Reload()
+	if err == nil || !strings.Contains(err.Error(), "schedule rejected") {
+		t.Fatalf("Expected the hook error, got %v", err)
+	}
+
+	if len(result.Applied) != 0 || len(result.RolledBack) != 1 || result.RolledBack[0].Key != "click_burst" {
+		t.Errorf("Expected click_burst to be rolled back, got %+v", result)
+	}
+	if reloader.Current() != cfg || applied != 40 {
+		t.Errorf("Expected the running configuration to be kept and restored, got %d and %d", reloader.Current().ClickBurst, applied)
+	}
+}
+
+// TestRuntimeMiddleware tests swapping the CORS origins of a running app
+func TestRuntimeMiddleware(t *testing.T) {
+	app := fiber.New()
+	runtime := middleware.SetupMiddleware(app, middleware.Options{
+		RequestTimeout: time.Second,
+		CORS:           middleware.CORSPolicy{Origins: []string{"https://clickflag.com"}},
+	})
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	get := func(origin string) (int, string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin")
+	}
+
+	if status, allowed := get("https://clickflag.com"); status != 200 || allowed != "https://clickflag.com" {
+		t.Errorf("Expected 200 with the origin allowed, got %d and %q", status, allowed)
+	}
+
+	runtime.SetCORS(middleware.CORSPolicy{Origins: []string{"https://example.com"}}, nil)
+
+	if status, allowed := get("https://example.com"); status != 200 || allowed != "https://example.com" {
+		t.Errorf("Expected the new origin to apply, got %d and %q", status, allowed)
+	}
+	if _, allowed := get("https://clickflag.com"); allowed != "" {
+		t.Errorf("Expected the old origin to be rejected, got %q", allowed)
+	}
+}
+
+// TestSetLogLevel tests changing the level of the default logger at runtime
+func TestSetLogLevel(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		logging.SetLevel("info")
+		slog.SetDefault(previous)
+	})
+
+	var buf bytes.Buffer
+	if _, err := logging.Setup(logging.Options{Level: "warn", Output: &buf}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	logger := slog.Default().With("component", "test")
+
+	logger.Info("hidden")
+	if err := logging.SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	logger.Debug("shown")
+
+	lines := decodeLogLines(t, &buf)
+	if len(lines) != 1 || lines[0]["msg"] != "shown" {
+		t.Errorf("Expected only the message logged after SetLevel, got %v", lines)
+	}
+	if err := logging.SetLevel("verbose"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
+
+// TestSetSchedule tests replacing the flush schedule of a running processor
+func TestSetSchedule(t *testing.T) {
+	bp := processor.NewBackgroundProcessor(cache.NewCache(), database.NewMemoryStore(), nil, "@every 1h", "")
+	bp.Start()
+	defer bp.Stop()
+
+	if err := bp.SetSchedule("every second"); err == nil {
+		t.Error("Expected an invalid cron expression to be rejected")
+	}
+	if err := bp.SetSchedule("*/10 * * * * *"); err != nil {
+		t.Fatalf("SetSchedule failed: %v", err)
+	}
+	if bp.Schedule() != "*/10 * * * * *" {
+		t.Errorf("Unexpected schedule %q", bp.Schedule())
+	}
+}
+
+// TestReloadSlowFlushSchedule tests that a flush schedule slower than ready_max_flush_age
+// is rejected before any hook runs
+func TestReloadSlowFlushSchedule(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", "flush_schedule: \"@every 10s\"\n")
+	args := []string{"-config", path}
+	cfg, _, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	reloader := config.NewReloader(cfg, args)
+
+	hookRan := false
+	reloader.OnReload(func(old, updated *config.Config) error {
+		hookRan = true
+		return nil
+	})
+
+	for _, schedule := range []string{"@every 30s", "0 * * * * *", "0,10 * * * * *"} {
+		if err := os.WriteFile(path, []byte("flush_schedule: \""+schedule+"\"\n"), 0644); err != nil {
+			t.Fatalf("Failed to rewrite config file: %v", err)
+		}
+		if _, err := reloader.Reload(); err == nil || !strings.Contains(err.Error(), "ready_max_flush_age") {
+			t.Errorf("Expected %q to be rejected, got %v", schedule, err)
+		}
+	}
+	if hookRan || reloader.Current().FlushSchedule != "@every 10s" {
+		t.Errorf("Expected the running schedule to be kept, got %q", reloader.Current().FlushSchedule)
+	}
+
+	if err := os.WriteFile(path, []byte("flush_schedule: \"@every 1m\"\nready_max_flush_age: 2m\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	if _, err := reloader.Reload(); err != nil {
+		t.Errorf("Expected a longer ready_max_flush_age to allow the schedule, got %v", err)
+	}
+}
+
+// TestAdminReloadConfig tests the reload endpoint and its audit entries
+func TestAdminReloadConfig(t *testing.T) {
+	path := writeConfigFile(t, "clickflag.yaml", "log_level: info\n")
+	args := []string{"-config", path}
+	cfg, _, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	store := database.NewMemoryStore()
+	cacheInstance := cache.NewCache()
+	adminHandler := handlers.NewAdminHandler(store, cacheInstance, processor.NewBackgroundProcessor(cacheInstance, store, nil, "@every 1h", ""))
+
+	app := fiber.New()
+	admin := app.Group("/admin/v1", middleware.AdminAuth(auth.NewAuthenticator(map[string]string{"alice": adminTestKey}, "")))
+	admin.Post("/config/reload", adminHandler.ReloadConfig)
+
+	if status, _ := adminRequest(t, app, "POST", "/admin/v1/config/reload", "", adminTestKey); status != fiber.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a reloader, got %d", status)
+	}
+
+	adminHandler.SetReloader(config.NewReloader(cfg, args))
+	if err := os.WriteFile(path, []byte("log_level: info\nflush_schedule: \"@every 10s\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	status, response := adminRequest(t, app, "POST", "/admin/v1/config/reload", "", adminTestKey)
+	if status != fiber.StatusOK || !response.Success {
+		t.Fatalf("Expected the reload to succeed, got %d: %s", status, response.Message)
+	}
+
+	if err := os.WriteFile(path, []byte("log_level: loud\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	status, response = adminRequest(t, app, "POST", "/admin/v1/config/reload", "", adminTestKey)
+	if status != fiber.StatusUnprocessableEntity || !strings.Contains(response.Message, "log_level") {
+		t.Errorf("Expected 422 naming log_level, got %d: %s", status, response.Message)
+	}
+
+	entries, err := store.AuditLog(context.Background(), 10)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %+v, %v", entries, err)
+	}
+	if entries[1].Actor != "alice" || !strings.Contains(entries[1].Detail, "flush_schedule (*/5 * * * * * -> @every 10s)") {
+		t.Errorf("Unexpected audit entry %+v", entries[1])
+	}
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/migrations"
+	"clickflag-go-backend/processor"
+)
+
+// countsByCode loads every country from the store keyed by country code
+func countsByCode(t *testing.T, store database.Store) map[string]int {
+	t.Helper()
+
+	countries, err := store.LoadCountries(context.Background())
+	if err != nil {
+		t.Fatalf("LoadCountries failed: %v", err)
+	}
+
+	counts := make(map[string]int, len(countries))
+	for _, country := range countries {
+		counts[country.CountryCode] = country.Value
+	}
+	return counts
+}
+
+// testStoreIncrements runs the shared Store contract against an implementation
+func testStoreIncrements(t *testing.T, store database.Store) {
+	before := countsByCode(t, store)
+	if len(before) != 195 {
+		t.Fatalf("Expected 195 countries, got %d", len(before))
+	}
+
+	if err := store.ApplyIncrements(context.Background(), map[string]int32{"TR": 3, "US": 1}, time.Now()); err != nil {
+		t.Fatalf("ApplyIncrements failed: %v", err)
+	}
+
+	after := countsByCode(t, store)
+	if after["TR"] != before["TR"]+3 {
+		t.Errorf("Expected TR to increase by 3, got %d -> %d", before["TR"], after["TR"])
+	}
+	if after["US"] != before["US"]+1 {
+		t.Errorf("Expected US to increase by 1, got %d -> %d", before["US"], after["US"])
+	}
+
+	// A batch with an unknown country is rolled back as a whole
+	err := store.ApplyIncrements(context.Background(), map[string]int32{"XX": 1, "TR": 5}, time.Now())
+	var incrementErr *database.IncrementError
+	if !errors.As(err, &incrementErr) || !errors.Is(incrementErr.Failed["XX"], database.ErrCountryNotFound) {
+		t.Errorf("Expected IncrementError for unknown country, got %v", err)
+	}
+
+	if rolledBack := countsByCode(t, store); rolledBack["TR"] != after["TR"] {
+		t.Errorf("Failed batch should not change TR, got %d -> %d", after["TR"], rolledBack["TR"])
+	}
+}
+
+// failingStore is a memory store whose writes always fail
+type failingStore struct {
+	*database.MemoryStore
+}
+
+// ApplyIncrements always fails without storing anything
+func (s failingStore) ApplyIncrements(ctx context.Context, increments map[string]int32, at time.Time) error {
+	return errors.New("database is locked")
+}
+
+// TestMemoryStore tests the in-memory store
+func TestMemoryStore(t *testing.T) {
+	testStoreIncrements(t, database.NewMemoryStore())
+}
+
+// TestSQLiteStore tests the SQLite store on a migrated temporary database
+func TestSQLiteStore(t *testing.T) {
+	db := openTestDB(t)
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	testStoreIncrements(t, database.NewSQLiteStore(db))
+}
+
+// TestPostgresStore tests the PostgreSQL store when TEST_POSTGRES_URL points at a scratch database
+func TestPostgresStore(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	store, err := database.OpenPostgresStore(url, "", database.DefaultPoolOptions())
+	if err != nil {
+		t.Fatalf("Failed to open PostgreSQL store: %v", err)
+	}
+	defer store.Close()
+
+	testStoreIncrements(t, store)
+}
+
+// TestFlushRetriesFailedBatch tests that a failed batch is put back into pending updates
+func TestFlushRetriesFailedBatch(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.AddPendingUpdateBy("TR", 4)
+	cacheInstance.AddPendingUpdateBy("US", 2)
+
+	bp := processor.NewBackgroundProcessor(cacheInstance, failingStore{database.NewMemoryStore()}, nil, "@every 1h", "")
+
+	result, err := bp.Flush(context.Background())
+	if err == nil {
+		t.Fatal("Flush should fail when the store fails")
+	}
+
+	retried := result.RetriedCountries()
+	if len(retried) != 2 || retried[0] != "TR" || retried[1] != "US" {
+		t.Errorf("Expected TR and US to be retried, got %v", retried)
+	}
+
+	pending := cacheInstance.GetPendingUpdates()
+	if pending["TR"] != 4 || pending["US"] != 2 {
+		t.Errorf("Failed batch should be back in pending updates, got %v", pending)
+	}
+}
+
+// TestShutdownSpillsFailedBatch tests that a failed final flush is spilled to the recovery file
+func TestShutdownSpillsFailedBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recovery.json")
+	cacheInstance := cache.NewCache()
+	cacheInstance.AddPendingUpdateBy("FR", 3)
+
+	bp := processor.NewBackgroundProcessor(cacheInstance, failingStore{database.NewMemoryStore()}, nil, "@every 1h", path)
+	if err := bp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown should spill instead of failing: %v", err)
+	}
+
+	spilled, err := processor.LoadRecoveryFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load recovery file: %v", err)
+	}
+	if spilled["FR"] != 3 {
+		t.Errorf("Expected 3 spilled clicks for FR, got %d", spilled["FR"])
+	}
+}
+
+// TestProcessorShutdownFlushesToStore tests that the final flush writes pending clicks to the store
+func TestProcessorShutdownFlushesToStore(t *testing.T) {
+	store := database.NewMemoryStore()
+	cacheInstance := cache.NewCache()
+
+	cacheInstance.AddPendingUpdateBy("DE", 7)
+
+	bp := processor.NewBackgroundProcessor(cacheInstance, store, nil, "@every 1h", "")
+	if err := bp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if counts := countsByCode(t, store); counts["DE"] != 7 {
+		t.Errorf("Expected DE to be 7 after shutdown flush, got %d", counts["DE"])
+	}
+
+	if cacheInstance.HasPendingUpdates() {
+		t.Error("Pending updates should be drained after shutdown flush")
+	}
+}
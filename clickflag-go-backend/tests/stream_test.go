@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/stream"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestBroadcasterFanOut tests that every subscriber receives published changes
+func TestBroadcasterFanOut(t *testing.T) {
+	b := stream.NewBroadcaster(0, 8)
+
+	first, err := b.Subscribe(0)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	second, err := b.Subscribe(0)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	b.Publish(map[string]int{"TR": 10}, map[string]int{"TR": 1})
+	b.Publish(map[string]int{}, map[string]int{}) // Nothing changed, nothing is sent
+
+	for _, sub := range []*stream.Subscription{first, second} {
+		event := <-sub.Events()
+		if event.Counts["TR"] != 10 || event.ID != sub.LastID+1 {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		select {
+		case event := <-sub.Events():
+			t.Errorf("Empty change set should not be published, got %+v", event)
+		default:
+		}
+	}
+}
+
+// TestBroadcasterResume tests resuming from a Last-Event-ID
+func TestBroadcasterResume(t *testing.T) {
+	b := stream.NewBroadcaster(0, 2)
+
+	sub, _ := b.Subscribe(0)
+	start := sub.LastID
+	sub.Close()
+
+	b.Publish(map[string]int{"TR": 1}, map[string]int{"TR": 1})
+	b.Publish(map[string]int{"US": 2}, map[string]int{"US": 1})
+	b.Publish(map[string]int{"DE": 3}, map[string]int{"DE": 1})
+
+	// Events after start+1 are still buffered
+	resumed, err := b.Subscribe(start + 1)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if !resumed.Resumed || len(resumed.Missed) != 2 || resumed.Missed[0].Counts["US"] != 2 {
+		t.Errorf("Expected to resume with 2 missed events, got %+v", resumed)
+	}
+
+	// The first event fell out of the buffer, so a snapshot is needed
+	if stale, _ := b.Subscribe(start); stale.Resumed {
+		t.Error("Resuming past the buffer should not be possible")
+	}
+
+	// IDs from the future (a previous process) also need a snapshot
+	if future, _ := b.Subscribe(start + 100); future.Resumed {
+		t.Error("Resuming from an unknown ID should not be possible")
+	}
+}
+
+// TestBroadcasterSubscriberCap tests the subscriber limit
+func TestBroadcasterSubscriberCap(t *testing.T) {
+	b := stream.NewBroadcaster(1, 8)
+
+	sub, err := b.Subscribe(0)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if _, err := b.Subscribe(0); !errors.Is(err, stream.ErrTooManySubscribers) {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+
+	sub.Close()
+	if _, err := b.Subscribe(0); err != nil {
+		t.Errorf("Subscribing after a slot was freed should work, got %v", err)
+	}
+}
+
+// TestBroadcasterDropsSlowSubscriber tests that a subscriber that stops reading is dropped
+func TestBroadcasterDropsSlowSubscriber(t *testing.T) {
+	b := stream.NewBroadcaster(0, 8)
+	sub, _ := b.Subscribe(0)
+
+	for i := 0; i < 100; i++ {
+		b.Publish(map[string]int{"TR": i}, map[string]int{"TR": 1})
+	}
+
+	if b.SubscriberCount() != 0 {
+		t.Errorf("Slow subscriber should be dropped, %d left", b.SubscriberCount())
+	}
+
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	if received == 0 {
+		t.Error("Buffered events should still be delivered before the channel closes")
+	}
+}
+
+// TestStreamEndpoint tests the SSE snapshot and change events
+func TestStreamEndpoint(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 5}, {CountryCode: "US", Value: 1}})
+
+	b := stream.NewBroadcaster(0, 8)
+	app := fiber.New()
+	app.Get("/api/v1/stream", handlers.NewStreamHandler(cacheInstance, b, time.Hour).Stream)
+
+	// Publish once the handler has subscribed, then end the stream
+	go func() {
+		for b.SubscriberCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		deltas := cacheInstance.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 6}, {CountryCode: "US", Value: 1}})
+		b.Publish(map[string]int{"TR": 6}, deltas)
+		b.Close()
+	}()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/stream", nil), 5000)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", contentType)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	if !strings.Contains(text, "event: snapshot\n") || !strings.Contains(text, `data: {"TR":5,"US":1}`) {
+		t.Errorf("Expected a snapshot of all counts, got %q", text)
+	}
+	if !strings.Contains(text, "event: counts\n") || !strings.Contains(text, `data: {"TR":6}`) {
+		t.Errorf("Expected only the changed country, got %q", text)
+	}
+}
+
+// TestStreamEndpointResume tests that Last-Event-ID replays missed events instead of a snapshot
+func TestStreamEndpointResume(t *testing.T) {
+	b := stream.NewBroadcaster(0, 8)
+	sub, _ := b.Subscribe(0)
+	sub.Close()
+	b.Publish(map[string]int{"FR": 9}, map[string]int{"FR": 1})
+
+	app := fiber.New()
+	app.Get("/api/v1/stream", handlers.NewStreamHandler(cache.NewCache(), b, time.Hour).Stream)
+
+	go func() {
+		for b.SubscriberCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		b.Close()
+	}()
+
+	req := httptest.NewRequest("GET", "/api/v1/stream", nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(sub.LastID, 10))
+	resp, err := app.Test(req, 5000)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	if strings.Contains(text, "event: snapshot") {
+		t.Errorf("Resumed stream should not send a snapshot, got %q", text)
+	}
+	if !strings.Contains(text, "id: "+strconv.FormatUint(sub.LastID+1, 10)+"\nevent: counts\ndata: {\"FR\":9}") {
+		t.Errorf("Expected the missed FR event, got %q", text)
+	}
+
+	// A closed broadcaster refuses new streams
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/stream", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("Expected 503 from a closed broadcaster, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/database"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/middleware"
+	"clickflag-go-backend/migrations"
+	"clickflag-go-backend/processor"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that records every span for the duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// spansByName indexes ended spans by name
+func spansByName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+// TestRequestID tests that valid client IDs are kept and missing or malformed ones replaced
+func TestRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.RequestID)
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(middleware.GetRequestID(c))
+	})
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"bad id\nwith newline", false},
+		{strings.Repeat("a", 200), false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set(fiber.HeaderXRequestID, tt.header)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		id := resp.Header.Get(fiber.HeaderXRequestID)
+		if id == "" {
+			t.Errorf("Expected a request ID for header %q", tt.header)
+		}
+		if (id == tt.header) != tt.keep {
+			t.Errorf("Header %q: keep=%v, got response ID %q", tt.header, tt.keep, id)
+		}
+	}
+}
+
+// TestTracingHTTPSpans tests that handler spans continue the caller's trace
+func TestTracingHTTPSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	app := fiber.New()
+	app.Use(middleware.RequestID)
+	app.Use(middleware.Tracing)
+	app.Post("/api/v1/countries", handlers.NewCountryHandler(cache.NewCache(), nil).AddCountry)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("POST", "/api/v1/countries", strings.NewReader(`{"country_code":"TR"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	spans := spansByName(recorder)
+	server, ok := spans["POST /api/v1/countries"]
+	if !ok {
+		t.Fatalf("Expected a server span named by route, got %v", spans)
+	}
+	if server.SpanContext().TraceID().String() != traceID {
+		t.Errorf("Server span should continue trace %s, got %s", traceID, server.SpanContext().TraceID())
+	}
+
+	add, ok := spans["AddPendingUpdate"]
+	if !ok {
+		t.Fatal("Expected an AddPendingUpdate span")
+	}
+	if add.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("AddPendingUpdate should be a child of the server span")
+	}
+}
+
+// TestTracingFlushAndSQLSpans tests the flush span and a span per SQL statement below it
+func TestTracingFlushAndSQLSpans(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	recorder := recordSpans(t)
+
+	cacheInstance := cache.NewCache()
+	cacheInstance.AddPendingUpdateBy("TR", 2)
+	cacheInstance.AddPendingUpdateBy("US", 1)
+
+	bp := processor.NewBackgroundProcessor(cacheInstance, database.NewSQLiteStore(db), nil, "@every 1h", "")
+	if _, err := bp.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	var flush sdktrace.ReadOnlySpan
+	statements := map[string]int{}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "Flush" {
+			flush = span
+			continue
+		}
+		statements[span.Name()]++
+	}
+	if flush == nil {
+		t.Fatal("Expected a Flush span")
+	}
+
+	if statements["UPDATE countries"] != 2 || statements["INSERT country_clicks_minute"] != 2 || statements["SELECT countries"] != 1 {
+		t.Errorf("Expected a span per statement, got %v", statements)
+	}
+
+	for _, span := range recorder.Ended() {
+		if span != flush && span.Parent().SpanID() != flush.SpanContext().SpanID() {
+			t.Errorf("SQL span %s should be a child of the flush span", span.Name())
+		}
+	}
+}
+
+// TestTracingAdminAndMigrationSpans tests that migrations and admin writes get a span per statement
+func TestTracingAdminAndMigrationSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	db := openTestDB(t)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	spans := spansByName(recorder)
+	if spans["MIGRATE 001_create_countries_table"] == nil || spans["INSERT schema_migrations"] == nil {
+		t.Errorf("Expected migration spans, got %v", spanNames(recorder))
+	}
+
+	store := database.NewSQLiteStore(db)
+	ctx := context.Background()
+	if _, err := store.SetCountryValue(ctx, "TR", 5, "alice"); err != nil {
+		t.Fatalf("SetCountryValue failed: %v", err)
+	}
+	if _, err := store.ResetCounts(ctx, "alice"); err != nil {
+		t.Fatalf("ResetCounts failed: %v", err)
+	}
+
+	statements := map[string]int{}
+	for _, span := range recorder.Ended() {
+		statements[span.Name()]++
+	}
+	if statements["UPDATE countries"] != 2 || statements["INSERT admin_audit"] != 2 || statements["SELECT countries"] != 2 {
+		t.Errorf("Expected the UPDATE and audit INSERT spans of a set and a reset, got %v", statements)
+	}
+
+	for _, attr := range spansByName(recorder)["UPDATE countries"].Attributes() {
+		if attr.Key == "db.query.text" && !strings.Contains(attr.Value.AsString(), "SET value = 0") {
+			t.Errorf("Expected the last UPDATE span to be the reset, got %q", attr.Value.AsString())
+		}
+	}
+}
+
+// spanNames lists the names of the ended spans
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
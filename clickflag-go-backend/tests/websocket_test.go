@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clickflag-go-backend/cache"
+	"clickflag-go-backend/handlers"
+	"clickflag-go-backend/models"
+	"clickflag-go-backend/ratelimit"
+	"clickflag-go-backend/stream"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// wsFrame is a JSON frame received from the WebSocket endpoint
+type wsFrame struct {
+	Type    string         `json:"type"`
+	ID      uint64         `json:"id"`
+	Data    map[string]int `json:"data"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+}
+
+// startWebSocketServer serves the WebSocket route on a random local port and returns its URL
+func startWebSocketServer(t *testing.T, cacheInstance *cache.Cache, b *stream.Broadcaster, burst int, clickLimiter *ratelimit.Limiter) string {
+	t.Helper()
+
+	wsHandler := handlers.NewWebSocketHandler(cacheInstance, nil, b, time.Minute, 1, burst)
+	if clickLimiter != nil {
+		wsHandler.SetClickLimiter(clickLimiter)
+	}
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/api/v1/ws", wsHandler.Upgrade, wsHandler.Handler())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() {
+		b.Close()
+		app.Shutdown()
+	})
+
+	return "ws://" + listener.Addr().String() + "/api/v1/ws"
+}
+
+// dialWebSocket connects to the endpoint and fails the test on error
+func dialWebSocket(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	return conn
+}
+
+// readJSONFrame reads the next text frame
+func readJSONFrame(t *testing.T, conn *websocket.Conn) wsFrame {
+	t.Helper()
+
+	var frame wsFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	return frame
+}
+
+// TestWebSocketClicksAndDeltas tests clicking over a WebSocket and receiving deltas
+func TestWebSocketClicksAndDeltas(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.RefreshCountries([]models.Country{{CountryCode: "TR", Value: 5}})
+	b := stream.NewBroadcaster(0, 8)
+
+	conn := dialWebSocket(t, startWebSocketServer(t, cacheInstance, b, 2, nil))
+
+	snapshot := readJSONFrame(t, conn)
+	if snapshot.Type != "snapshot" || snapshot.Data["TR"] != 5 {
+		t.Fatalf("Expected a snapshot with TR=5, got %+v", snapshot)
+	}
+
+	conn.WriteJSON(map[string]string{"c": "TR"})
+	conn.WriteJSON(map[string]string{"c": "XX"})
+	if frame := readJSONFrame(t, conn); frame.Type != "error" || frame.Code != "XX" {
+		t.Errorf("Expected an error for XX, got %+v", frame)
+	}
+
+	// The burst of 2 allows one more click, the third is rate limited
+	conn.WriteJSON(map[string]string{"c": "TR"})
+	conn.WriteJSON(map[string]string{"c": "TR"})
+	if frame := readJSONFrame(t, conn); frame.Type != "error" || frame.Message == "" {
+		t.Errorf("Expected a rate limit error, got %+v", frame)
+	}
+
+	if pending := cacheInstance.GetPendingUpdates(); pending["TR"] != 2 {
+		t.Errorf("Expected 2 pending TR clicks, got %d", pending["TR"])
+	}
+
+	b.Publish(map[string]int{"TR": 7}, map[string]int{"TR": 2})
+	delta := readJSONFrame(t, conn)
+	if delta.Type != "delta" || delta.Data["TR"] != 2 || delta.ID != snapshot.ID+1 {
+		t.Errorf("Expected a TR delta of 2, got %+v", delta)
+	}
+}
+
+// TestWebSocketBinaryFrames tests the compact binary frame format
+func TestWebSocketBinaryFrames(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	cacheInstance.RefreshCountries([]models.Country{{CountryCode: "DE", Value: 3}, {CountryCode: "TR", Value: 1}})
+	b := stream.NewBroadcaster(0, 8)
+
+	conn := dialWebSocket(t, startWebSocketServer(t, cacheInstance, b, 10, nil)+"?format=binary")
+
+	messageType, payload, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if messageType != websocket.BinaryMessage || len(payload) != 9+2*6 || payload[0] != 1 {
+		t.Fatalf("Unexpected binary snapshot %v", payload)
+	}
+	if string(payload[9:11]) != "DE" || binary.BigEndian.Uint32(payload[11:15]) != 3 {
+		t.Errorf("Expected DE=3 as the first entry, got %v", payload[9:15])
+	}
+
+	conn.WriteMessage(websocket.BinaryMessage, []byte("TR"))
+	conn.WriteMessage(websocket.BinaryMessage, []byte("TUR"))
+
+	_, payload, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read error frame: %v", err)
+	}
+	var frame wsFrame
+	if err := json.Unmarshal(payload, &frame); err != nil || frame.Type != "error" {
+		t.Errorf("Expected a JSON error for a malformed binary click, got %s", payload)
+	}
+
+	if pending := cacheInstance.GetPendingUpdates(); pending["TR"] != 1 {
+		t.Errorf("Expected 1 pending TR click, got %d", pending["TR"])
+	}
+}
+
+// TestWebSocketSharedClickLimit tests that the connections of one client IP share its click limit
+func TestWebSocketSharedClickLimit(t *testing.T) {
+	cacheInstance := cache.NewCache()
+	b := stream.NewBroadcaster(0, 8)
+	url := startWebSocketServer(t, cacheInstance, b, 10, ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 2}, ratelimit.Rule{}, 100))
+
+	first, second := dialWebSocket(t, url), dialWebSocket(t, url)
+	readJSONFrame(t, first)
+	readJSONFrame(t, second)
+
+	// Frames are read in order, so the error for XX means the click was handled
+	first.WriteJSON(map[string]string{"c": "TR"})
+	first.WriteJSON(map[string]string{"c": "XX"})
+	if frame := readJSONFrame(t, first); frame.Code != "XX" {
+		t.Fatalf("Expected an error for XX, got %+v", frame)
+	}
+
+	// The second connection only has the one click left of the shared burst of 2
+	second.WriteJSON(map[string]string{"c": "TR"})
+	second.WriteJSON(map[string]string{"c": "TR"})
+	if frame := readJSONFrame(t, second); frame.Type != "error" || frame.Code != "TR" {
+		t.Errorf("Expected a rate limit error, got %+v", frame)
+	}
+
+	if pending := cacheInstance.GetPendingUpdates(); pending["TR"] != 2 {
+		t.Errorf("Expected 2 pending TR clicks, got %d", pending["TR"])
+	}
+}
+
+// TestWebSocketShutdown tests that Shutdown closes live connections and refuses new ones
+func TestWebSocketShutdown(t *testing.T) {
+	b := stream.NewBroadcaster(0, 8)
+	wsHandler := handlers.NewWebSocketHandler(cache.NewCache(), nil, b, time.Minute, 1, 5)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/api/v1/ws", wsHandler.Upgrade, wsHandler.Handler())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() {
+		b.Close()
+		app.Shutdown()
+	})
+	url := "ws://" + listener.Addr().String() + "/api/v1/ws"
+
+	conn := dialWebSocket(t, url)
+	if frame := readJSONFrame(t, conn); frame.Type != "snapshot" {
+		t.Fatalf("Expected a snapshot first, got %+v", frame)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wsHandler.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected the connection to be closed as going away, got %v", err)
+	}
+
+	late := dialWebSocket(t, url)
+	if _, _, err := late.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a connection after Shutdown to be refused, got %v", err)
+	}
+}
+
+// TestWebSocketRequiresUpgrade tests that plain HTTP requests are rejected
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	wsHandler := handlers.NewWebSocketHandler(cache.NewCache(), nil, stream.NewBroadcaster(0, 8), time.Minute, 1, 1)
+	app := fiber.New()
+	app.Get("/api/v1/ws", wsHandler.Upgrade, wsHandler.Handler())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/ws", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUpgradeRequired {
+		t.Errorf("Expected 426, got %d", resp.StatusCode)
+	}
+}
+
+// TestTokenBucket tests bursts and refill of the token bucket
+func TestTokenBucket(t *testing.T) {
+	bucket := ratelimit.NewTokenBucket(2, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !bucket.AllowAt(now) {
+			t.Fatalf("Burst token %d should be allowed", i+1)
+		}
+	}
+	if bucket.AllowAt(now) {
+		t.Error("Empty bucket should refuse")
+	}
+
+	// Two tokens per second: half a second later there is one token
+	if !bucket.AllowAt(now.Add(500 * time.Millisecond)) {
+		t.Error("Refilled token should be allowed")
+	}
+	if bucket.AllowAt(now.Add(500 * time.Millisecond)) {
+		t.Error("Only one token should have been refilled")
+	}
+}